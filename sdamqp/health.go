@@ -0,0 +1,16 @@
+package sdamqp
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+)
+
+// HealthCheck 返回检查连接是否关闭的函数，可以用于sdecho.Health
+func HealthCheck(cc *ChannelConn) func(context.Context) error {
+	return func(context.Context) error {
+		if cc.Conn == nil || cc.Conn.IsClosed() {
+			return sderr.New("AMQP connection closed")
+		}
+		return nil
+	}
+}
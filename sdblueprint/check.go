@@ -204,6 +204,22 @@ func (m *module) checkSelf() error {
 			if t1.Dirname == "" {
 				return sderr.New("no dir in generate sql task")
 			}
+		case *ModuleTaskGeneratePostgresDDL:
+			if t1.Dirname == "" {
+				return sderr.New("no dir in generate sql task")
+			}
+		case *ModuleTaskGenerateSqliteDDL:
+			if t1.Dirname == "" {
+				return sderr.New("no dir in generate sql task")
+			}
+		case *ModuleTaskGenerateCrud:
+			if t1.Dirname == "" {
+				return sderr.New("no dir in generate CRUD task")
+			}
+		case *ModuleTaskGenerateTypeScriptModel:
+			if t1.Dirname == "" {
+				return sderr.New("no dir in generate TypeScript model task")
+			}
 		default:
 			return sderr.NewWith("illegal task", sderr.Attrs{"task": reflect.TypeOf(t1).String(), "model": m.id})
 		}
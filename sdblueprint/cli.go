@@ -1,6 +1,7 @@
 package sdblueprint
 
 import (
+	"fmt"
 	"github.com/gaorx/stardust5/sdcodegen"
 	"github.com/gaorx/stardust5/sderr"
 	"github.com/gaorx/stardust5/sdfile"
@@ -16,7 +17,7 @@ import (
 func (bp *Blueprint) RunCli() {
 	app := &cli.App{
 		Name:  "Blueprint tool",
-		Usage: "go run blueprint.go gen|mock-db",
+		Usage: "go run blueprint.go gen|mock-db|migrate|snapshot",
 		Commands: []*cli.Command{
 			{
 				Name:  "gen",
@@ -39,6 +40,50 @@ func (bp *Blueprint) RunCli() {
 					return bp.cliMockDB(cc)
 				},
 			},
+			{
+				Name:  "migrate",
+				Usage: "generate migration scripts from database or snapshot",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "dialect",
+						Usage: "mysql or postgres, only for snapshot",
+						Value: DialectMysql,
+					},
+					&cli.StringFlag{
+						Name:  "dir",
+						Usage: "directory for migration scripts",
+						Value: "migrations",
+					},
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "migration name",
+					},
+					&cli.StringFlag{
+						Name:    "groups",
+						Aliases: []string{"g"},
+						Usage:   "for some group",
+					},
+					&cli.StringFlag{
+						Name:  "snapshot",
+						Usage: "compare with this snapshot file instead of database, and update it",
+					},
+					&cli.BoolFlag{
+						Name:  "allow-destructive",
+						Usage: "do not comment out drop statements",
+					},
+				},
+				Action: func(cc *cli.Context) error {
+					return bp.cliMigrate(cc)
+				},
+			},
+			{
+				Name:      "snapshot",
+				Usage:     "save snapshot of tables",
+				ArgsUsage: "<file>",
+				Action: func(cc *cli.Context) error {
+					return bp.cliSnapshot(cc)
+				},
+			},
 		},
 	}
 	if err := app.Run(os.Args); err != nil {
@@ -73,29 +118,113 @@ func (bp *Blueprint) cliGenerate(cc *cli.Context) error {
 }
 
 func (bp *Blueprint) cliMockDB(cc *cli.Context) error {
-	getEnv := func(key string, def string) string {
-		v := os.Getenv(key)
-		if v == "" {
-			return def
-		}
-		return v
+	addr, err := cliDBAddress()
+	if err != nil {
+		return err
+	}
+	groups := sdstrings.SplitNonempty(cc.String("groups"), ",", true)
+	bp1 := getSub(bp, groups)
+	if err := bp1.MockDB(addr); err != nil {
+		return sderr.WithStack(err)
 	}
-	dbDriver := getEnv("SD_DB_DRIVER", "")
-	dbDSN := getEnv("SD_DB_DSN", "")
+	return nil
+}
+
+func cliDBAddress() (sdgorm.Address, error) {
+	dbDriver := os.Getenv("SD_DB_DRIVER")
+	dbDSN := os.Getenv("SD_DB_DSN")
 	if dbDriver == "" {
-		return sderr.New("no env SD_DB_DRIVER")
+		return sdgorm.Address{}, sderr.New("no env SD_DB_DRIVER")
 	}
 	if dbDSN == "" {
-		return sderr.New("no env SD_DB_DSN")
+		return sdgorm.Address{}, sderr.New("no env SD_DB_DSN")
 	}
-	groups := sdstrings.SplitNonempty(cc.String("groups"), ",", true)
-	bp1 := getSub(bp, groups)
-	if err := bp1.MockDB(sdgorm.Address{
+	return sdgorm.Address{
 		Driver: dbDriver,
 		DSN:    dbDSN,
-	}); err != nil {
+	}, nil
+}
+
+func (bp *Blueprint) cliMigrate(cc *cli.Context) error {
+	groups := sdstrings.SplitNonempty(cc.String("groups"), ",", true)
+	bp1 := getSub(bp, groups)
+	// 指定了group时两边都只比较选中的表
+	var tableIds []string
+	if len(groups) > 0 {
+		tableIds = bp1.TableIds()
+	}
+	snapshotFn := cc.String("snapshot")
+	var from *Schema
+	var prev *Blueprint
+	if snapshotFn != "" {
+		dialect := cc.String("dialect")
+		from = &Schema{Dialect: dialect}
+		if sdfile.Exists(snapshotFn) {
+			data, err := os.ReadFile(snapshotFn)
+			if err != nil {
+				return sderr.WrapWith(err, "read snapshot error", snapshotFn)
+			}
+			prev, err = LoadSnapshot(data)
+			if err != nil {
+				return sderr.WithStack(err)
+			}
+			from, err = SchemaOf(prev, dialect)
+			if err != nil {
+				return sderr.WithStack(err)
+			}
+		}
+	} else {
+		addr, err := cliDBAddress()
+		if err != nil {
+			return err
+		}
+		db, err := sdgorm.Dial(addr, nil)
+		if err != nil {
+			return sderr.WithStack(err)
+		}
+		from, err = SchemaFromDB(db, lo.Map(bp1.Tables(), func(t Table, _ int) string {
+			return t.NameForDB()
+		})...)
+		if err != nil {
+			return sderr.WithStack(err)
+		}
+	}
+	buffs, err := bp.Generate(Migration{
+		From:             from,
+		Dirname:          cc.String("dir"),
+		Name:             cc.String("name"),
+		TableIds:         tableIds,
+		AllowDestructive: cc.Bool("allow-destructive"),
+	})
+	if err != nil {
+		return sderr.WithStack(err)
+	}
+	if len(buffs.Filenames()) <= 0 {
+		fmt.Println("no changes")
+	} else if err := buffs.Save("", sdcodegen.SimplePrint); err != nil {
 		return sderr.WithStack(err)
 	}
+	if snapshotFn != "" {
+		// 指定了group时只把选中的表合并到之前的快照中，其他表未生成的变更留到之后迁移
+		snapshot := bp
+		if len(groups) > 0 {
+			snapshot = mergeSnapshot(prev, bp, tableIds)
+		}
+		if err := os.WriteFile(snapshotFn, snapshot.Snapshot(), 0644); err != nil {
+			return sderr.WrapWith(err, "write snapshot error", snapshotFn)
+		}
+	}
+	return nil
+}
+
+func (bp *Blueprint) cliSnapshot(cc *cli.Context) error {
+	fn := cc.Args().First()
+	if fn == "" {
+		return sderr.New("./sdcli snapshot <file>")
+	}
+	if err := os.WriteFile(fn, bp.Snapshot(), 0644); err != nil {
+		return sderr.WrapWith(err, "write snapshot error", fn)
+	}
 	return nil
 }
 
@@ -8,6 +8,8 @@ import (
 	"github.com/gaorx/stardust5/sdslog"
 	"github.com/gaorx/stardust5/sdtemplate"
 	"github.com/samber/lo"
+	"strconv"
+	"strings"
 )
 
 type BunModel struct {
@@ -15,12 +17,19 @@ type BunModel struct {
 	TableIds     []string
 	FileForModel string
 
+	// queries
+	WithQuery    bool
+	QueryIds     []string
+	FileForQuery string
+
 	// callback
 	OnHeader func(w sdcodegen.Writer, g *BunModel, bp *Blueprint)
 	OnModel  func(w sdcodegen.Writer, g *BunModel, bp *Blueprint, t Table)
+	OnQuery  func(w sdcodegen.Writer, g *BunModel, bp *Blueprint, q Query)
 
 	// options
-	Package string
+	Package        string
+	CheckSQLSyntax bool // 使用sdsqlparser(mysql语法)检查query中的SQL语法，sdsqlparser不支持其他方言所以默认关闭，ForModule中只生成mysql DDL的module会开启
 }
 
 var _ Generator = BunModel{}
@@ -59,13 +68,63 @@ func (g BunModel) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
 			if ok := lo.Try0(func() {
 				g.OnHeader(buff, &g, bp)
 			}); !ok {
-				return sderr.NewWith("blueprint generate GORM error", "on_header")
+				return sderr.NewWith("blueprint generate BUN error", "on_header")
 			}
 		}
 		if ok := lo.Try0(func() {
 			g.OnModel(buff, &g, bp, t)
 		}); !ok {
-			return sderr.NewWith("blueprint generate GORM error", "on_model")
+			return sderr.NewWith("blueprint generate BUN error", "on_model")
+		}
+	}
+
+	// queries
+	if !g.WithQuery {
+		return nil
+	}
+
+	queryIds := matchIds(bp.QueryIds(), g.QueryIds)
+	if len(queryIds) <= 0 {
+		return nil
+	}
+
+	// query filename
+	if g.FileForQuery == "" {
+		g.FileForQuery = g.FileForModel
+	}
+
+	// query callback
+	if g.OnQuery == nil {
+		g.OnQuery = onBunQuery
+	}
+
+	// generate query
+	for _, queryId := range queryIds {
+		q := bp.Query(queryId)
+		if q == nil {
+			panic(sderr.NewWith("not found query", queryId))
+		}
+		if err := checkQuerySQL(q, g.CheckSQLSyntax); err != nil {
+			return err
+		}
+		filename, err := executeTemplate(g.FileForQuery, map[string]any{"Id": q.Id()})
+		if err != nil {
+			return sderr.WithStack(err)
+		}
+
+		buff := buffs.Append(filename)
+		if buff.IsEmpty() {
+			if ok := lo.Try0(func() {
+				g.OnHeader(buff, &g, bp)
+			}); !ok {
+				return sderr.NewWith("blueprint generate BUN error", "on_header")
+			}
+		}
+
+		if ok := lo.Try0(func() {
+			g.OnQuery(buff, &g, bp, q)
+		}); !ok {
+			return sderr.NewWith("blueprint generate BUN error", "on_query")
 		}
 	}
 
@@ -73,7 +132,13 @@ func (g BunModel) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
 }
 
 func onBunHeader(w sdcodegen.Writer, g *BunModel, bp *Blueprint) {
-	if len(bp.tables) > 0 {
+	if g.WithQuery && len(bp.queries) > 0 {
+		sdgengo.Header(w, w.Filename(), g.Package, []string{
+			"context",
+			"github.com/gaorx/stardust5/sderr",
+			"github.com/uptrace/bun",
+		}).NL()
+	} else if len(bp.tables) > 0 {
 		sdgengo.Header(w, w.Filename(), g.Package, []string{
 			"github.com/uptrace/bun",
 		}).NL()
@@ -171,3 +236,100 @@ func onBunTable(w sdcodegen.Writer, g *BunModel, bp *Blueprint, t Table) {
 
 	w.NL()
 }
+
+func onBunQuery(w sdcodegen.Writer, _ *BunModel, _ *Blueprint, q Query) {
+	getGenParams := func() []sdgengo.NamedType {
+		pairs := []sdgengo.NamedType{
+			{Name: "ctx", Type: "context.Context"},
+			{Name: "db", Type: "bun.IDB"},
+		}
+		for _, param := range q.Params() {
+			pairs = append(pairs, sdgengo.NamedType{Name: param.Name(), Type: param.Type()})
+		}
+		return pairs
+	}
+
+	genExec := func(w sdcodegen.Writer, exec string, returnRowAffected bool) {
+		if !returnRowAffected {
+			w.I(1).FL("_, err := %s", exec)
+			w.I(1).L("if err != nil {")
+			w.I(2).L("return sderr.WithStack(err)")
+			w.I(1).L("}")
+			w.I(1).L("return nil")
+		} else {
+			w.I(1).FL("res, err := %s", exec)
+			w.I(1).L("if err != nil {")
+			w.I(2).L("return 0, sderr.WithStack(err)")
+			w.I(1).L("}")
+			w.I(1).L("n, err := res.RowsAffected()")
+			w.I(1).L("if err != nil {")
+			w.I(2).L("return 0, sderr.WithStack(err)")
+			w.I(1).L("}")
+			w.I(1).L("return n, nil")
+		}
+	}
+
+	raw := func() string {
+		sql, args := bunQuerySQL(q)
+		if len(args) <= 0 {
+			return fmt.Sprintf("db.NewRaw(%s)", strconv.Quote(sql))
+		}
+		return fmt.Sprintf("db.NewRaw(%s, %s)", strconv.Quote(sql), strings.Join(args, ", "))
+	}
+
+	execReturns := func() []sdgengo.NamedType {
+		if q.ReturnRowAffected() {
+			return sdgengo.Return("int64", "error")
+		}
+		return sdgengo.Return("error")
+	}
+
+	k := q.Kind()
+	switch k {
+	case QueryForCreate:
+		sdgengo.Func(w, q.Id(), getGenParams(), execReturns(), func(w sdcodegen.Writer) {
+			genExec(w, fmt.Sprintf("db.NewInsert().Model(%s).Exec(ctx)", q.ParamByIndex(0).Name()), q.ReturnRowAffected())
+		}).NL()
+	case QueryForUpdate:
+		sdgengo.Func(w, q.Id(), getGenParams(), execReturns(), func(w sdcodegen.Writer) {
+			genExec(w, fmt.Sprintf("db.NewUpdate().Model(%s).WherePK().Exec(ctx)", q.ParamByIndex(0).Name()), q.ReturnRowAffected())
+		}).NL()
+	case QueryForExec:
+		sdgengo.Func(w, q.Id(), getGenParams(), execReturns(), func(w sdcodegen.Writer) {
+			genExec(w, raw()+".Exec(ctx)", q.ReturnRowAffected())
+		}).NL()
+	case QueryForRecord:
+		typ := q.Result().Type()
+		sdgengo.Func(w, q.Id(), getGenParams(), sdgengo.Return(typ, "error"), func(w sdcodegen.Writer) {
+			if strings.HasPrefix(typ, "*") {
+				w.I(1).FL("row := new(%s)", strings.TrimPrefix(typ, "*"))
+				w.I(1).FL("if err := %s.Scan(ctx, row); err != nil {", raw())
+				w.I(2).L("return nil, sderr.WithStack(err)")
+			} else {
+				w.I(1).FL("var row %s", typ)
+				w.I(1).FL("if err := %s.Scan(ctx, &row); err != nil {", raw())
+				w.I(2).L("return row, sderr.WithStack(err)")
+			}
+			w.I(1).L("}")
+			w.I(1).L("return row, nil")
+		}).NL()
+	case QueryForRecords:
+		sdgengo.Func(w, q.Id(), getGenParams(), sdgengo.Return(q.Result().Type(), "error"), func(w sdcodegen.Writer) {
+			w.I(1).FL("var rows %s", q.Result().Type())
+			w.I(1).FL("if err := %s.Scan(ctx, &rows); err != nil {", raw())
+			w.I(2).L("return nil, sderr.WithStack(err)")
+			w.I(1).L("}")
+			w.I(1).L("return rows, nil")
+		}).NL()
+	case QueryForScalar:
+		sdgengo.Func(w, q.Id(), getGenParams(), sdgengo.Return(q.Result().Type(), "error"), func(w sdcodegen.Writer) {
+			w.I(1).FL("var r %s", q.Result().Type())
+			w.I(1).FL("if err := %s.Scan(ctx, &r); err != nil {", raw())
+			w.I(2).L("return r, sderr.WithStack(err)")
+			w.I(1).L("}")
+			w.I(1).L("return r, nil")
+		}).NL()
+	default:
+		panic(sderr.NewWith("illegal kind in query for generate code", sderr.Attrs{"kind": k, "q": q.Id()}))
+	}
+}
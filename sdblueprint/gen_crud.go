@@ -0,0 +1,464 @@
+package sdblueprint
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sdcodegen/sdgengo"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/gaorx/stardust5/sdstrings"
+	"github.com/samber/lo"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+const (
+	CrudDriverGorm = "gorm"
+	CrudDriverBun  = "bun"
+)
+
+// Crud 为表生成<T>Repo数据访问层，以及可选的<T>CrudAPI(sdecho.CrudAPI)
+// 只支持单列主键并且主键类型为string/int/int64的表，其他的表会被跳过
+// 生成的代码与GormModel/BunModel生成的model在同一个package中
+type Crud struct {
+	TableIds    []string
+	FileForRepo string
+	FileForAPI  string // 为空时不生成CrudAPI
+
+	// callback
+	OnRepo func(w sdcodegen.Writer, g *Crud, bp *Blueprint, t Table)
+	OnAPI  func(w sdcodegen.Writer, g *Crud, bp *Blueprint, t Table)
+
+	// options
+	Driver  string // gorm或者bun，默认为gorm
+	Package string
+}
+
+var _ Generator = Crud{}
+
+func (g Crud) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
+	tableIds := matchIds(bp.TableIds(), g.TableIds)
+	if len(tableIds) <= 0 {
+		return nil
+	}
+
+	// filename
+	if g.FileForRepo == "" {
+		return sderr.New("no filename on generate go code for repository")
+	}
+
+	// driver
+	if g.Driver == "" {
+		g.Driver = CrudDriverGorm
+	}
+	if g.Driver != CrudDriverGorm && g.Driver != CrudDriverBun {
+		return sderr.NewWith("illegal driver for crud", g.Driver)
+	}
+
+	// callback
+	if g.OnRepo == nil {
+		g.OnRepo = onCrudRepo
+	}
+	if g.OnAPI == nil {
+		g.OnAPI = onCrudAPI
+	}
+
+	appendBuff := func(file string, t Table) (*sdcodegen.Buffer, error) {
+		filename, err := executeTemplate(file, map[string]any{"Id": t.Id()})
+		if err != nil {
+			return nil, sderr.WithStack(err)
+		}
+		buff := buffs.Append(filename)
+		if buff.IsEmpty() {
+			sdgengo.Header(buff, buff.Filename(), g.Package, nil).NL()
+		}
+		return buff, nil
+	}
+
+	for _, tableId := range tableIds {
+		t := bp.Table(tableId)
+		if t == nil {
+			panic(sderr.NewWith("not found table", tableId))
+		}
+		if _, ok := crudIdColumn(t); !ok {
+			sdslog.With("t", t.Id()).Info("skip table without single string/int/int64 primary key for crud")
+			continue
+		}
+
+		buff, err := appendBuff(g.FileForRepo, t)
+		if err != nil {
+			return err
+		}
+		if ok := lo.Try0(func() {
+			g.OnRepo(buff, &g, bp, t)
+		}); !ok {
+			return sderr.NewWith("blueprint generate CRUD error", "on_repo")
+		}
+
+		if g.FileForAPI != "" {
+			buff, err := appendBuff(g.FileForAPI, t)
+			if err != nil {
+				return err
+			}
+			if ok := lo.Try0(func() {
+				g.OnAPI(buff, &g, bp, t)
+			}); !ok {
+				return sderr.NewWith("blueprint generate CRUD error", "on_api")
+			}
+		}
+	}
+	return nil
+}
+
+func onCrudRepo(w sdcodegen.Writer, g *Crud, _ *Blueprint, t Table) {
+	model := t.NameForGo()
+	repo := model + "Repo"
+	idCol, _ := crudIdColumn(t)
+	idTyp := idCol.Type().String()
+	isGorm := g.Driver == CrudDriverGorm
+
+	if isGorm {
+		sdgengo.AddImportPackages(w, []string{
+			"context",
+			"github.com/gaorx/stardust5/sderr",
+			"github.com/gaorx/stardust5/sdgorm",
+			"github.com/gaorx/stardust5/sdsql",
+			"gorm.io/gorm",
+		})
+	} else {
+		sdgengo.AddImportPackages(w, []string{
+			"context",
+			"github.com/gaorx/stardust5/sderr",
+			"github.com/gaorx/stardust5/sdbun",
+			"github.com/gaorx/stardust5/sdsql",
+			"github.com/uptrace/bun",
+		})
+	}
+	scopeTyp := lo.Ternary(isGorm, "func(*gorm.DB) *gorm.DB", "func(*bun.SelectQuery) *bun.SelectQuery")
+
+	// where 生成条件，gorm使用map(会quote列名)，bun使用bun.Ident
+	where := func(cols []Column, params []string) string {
+		if isGorm {
+			pairs := lo.Map(cols, func(c Column, i int) string {
+				return fmt.Sprintf("%s: %s", strconv.Quote(c.NameForDB()), params[i])
+			})
+			return fmt.Sprintf("map[string]any{%s}", strings.Join(pairs, ", "))
+		}
+		return strings.Join(lo.Map(cols, func(c Column, i int) string {
+			return fmt.Sprintf("q = q.Where(\"? = ?\", bun.Ident(%s), %s)", strconv.Quote(c.NameForDB()), params[i])
+		}), "\n")
+	}
+	bunScope := func(w sdcodegen.Writer, cols []Column, params []string) {
+		w.I(2).FL("func(q *bun.SelectQuery) *bun.SelectQuery {")
+		for _, line := range strings.Split(where(cols, params), "\n") {
+			w.I(3).L(line)
+		}
+		w.I(3).L("return q")
+		w.I(2).L("},")
+	}
+
+	// record id
+	if t.Method("RecordID") == nil {
+		w.FL("func (o *%s) RecordID() %s {", model, idTyp)
+		w.I(1).FL("return o.%s", idCol.Id())
+		w.L("}")
+		w.NL()
+	}
+
+	// repo
+	w.FL("// %s %s的数据访问", repo, model)
+	w.FL("type %s struct {", repo)
+	w.I(1).L(lo.Ternary(isGorm, "DB *gorm.DB", "DB bun.IDB"))
+	w.L("}")
+	w.NL()
+
+	// create
+	w.FL("func (r %s) Create(ctx context.Context, o *%s) error {", repo, model)
+	if isGorm {
+		w.I(1).L("return sderr.WithStack(r.DB.WithContext(ctx).Create(o).Error)")
+	} else {
+		w.I(1).L("_, err := r.DB.NewInsert().Model(o).Exec(ctx)")
+		w.I(1).L("return sderr.WithStack(err)")
+	}
+	w.L("}")
+	w.NL()
+
+	// update
+	w.L("// Update 根据主键更新o中的columns，columns为空时更新除主键外的所有列，主键为零值时返回错误")
+	w.FL("func (r %s) Update(ctx context.Context, o *%s, columns ...string) error {", repo, model)
+	w.I(1).FL("if o.%s == %s {", idCol.Id(), crudZeroOf(idTyp))
+	w.I(2).L("return sderr.New(\"update without primary key\")")
+	w.I(1).L("}")
+	if isGorm {
+		w.I(1).L("tx := r.DB.WithContext(ctx).Model(o)")
+		w.I(1).L("if len(columns) > 0 {")
+		w.I(2).L("tx = tx.Select(columns)")
+		w.I(1).L("} else {")
+		w.I(2).FL("tx = tx.Select(\"*\").Omit(%s)", strconv.Quote(idCol.NameForDB()))
+		w.I(1).L("}")
+		w.I(1).L("return sderr.WithStack(tx.Updates(o).Error)")
+	} else {
+		w.I(1).L("q := r.DB.NewUpdate().Model(o).WherePK()")
+		w.I(1).L("if len(columns) > 0 {")
+		w.I(2).L("q = q.Column(columns...)")
+		w.I(1).L("} else {")
+		w.I(2).FL("q = q.ExcludeColumn(%s)", strconv.Quote(idCol.NameForDB()))
+		w.I(1).L("}")
+		w.I(1).L("_, err := q.Exec(ctx)")
+		w.I(1).L("return sderr.WithStack(err)")
+	}
+	w.L("}")
+	w.NL()
+
+	// delete
+	w.FL("func (r %s) Delete(ctx context.Context, id %s) error {", repo, idTyp)
+	if isGorm {
+		w.I(1).FL("return sderr.WithStack(r.DB.WithContext(ctx).Where(%s).Delete(&%s{}).Error)", where([]Column{idCol}, []string{"id"}), model)
+	} else {
+		w.I(1).FL("_, err := r.DB.NewDelete().Model((*%s)(nil)).Where(\"? = ?\", bun.Ident(%s), id).Exec(ctx)", model, strconv.Quote(idCol.NameForDB()))
+		w.I(1).L("return sderr.WithStack(err)")
+	}
+	w.L("}")
+	w.NL()
+
+	// get
+	genFirst := func(name string, cols []Column) {
+		params := crudParamNames(cols)
+		decls := lo.Map(cols, func(c Column, i int) string { return params[i] + " " + crudColumnGoType(w, c) })
+		w.FL("func (r %s) %s(ctx context.Context, %s) (*%s, error) {", repo, name, strings.Join(decls, ", "), model)
+		if isGorm {
+			w.I(1).FL("return r.first(ctx, %s)", where(cols, params))
+		} else {
+			w.I(1).L("return r.first(ctx,")
+			bunScope(w, cols, params)
+			w.I(1).L(")")
+		}
+		w.L("}")
+		w.NL()
+	}
+	genList := func(name string, cols []Column) {
+		params := crudParamNames(cols)
+		decls := lo.Map(cols, func(c Column, i int) string { return params[i] + " " + crudColumnGoType(w, c) })
+		w.FL("func (r %s) %s(ctx context.Context, %s) ([]*%s, error) {", repo, name, strings.Join(decls, ", "), model)
+		w.I(1).L("return r.List(ctx,")
+		if isGorm {
+			w.I(2).L("func(tx *gorm.DB) *gorm.DB {")
+			w.I(3).FL("return tx.Where(%s)", where(cols, params))
+			w.I(2).L("},")
+		} else {
+			bunScope(w, cols, params)
+		}
+		w.I(1).L(")")
+		w.L("}")
+		w.NL()
+	}
+	genFirst("Get", []Column{idCol})
+	generated := map[string]bool{}
+	for _, idx := range t.Indexes() {
+		cols := lo.Map(idx.Columns(), func(colId string, _ int) Column { return t.Column(colId) })
+		suffix := "By" + strings.Join(lo.Map(cols, func(c Column, _ int) string { return c.Id() }), "And")
+		switch idx.Kind() {
+		case IndexUnique:
+			if !generated["Get"+suffix] {
+				generated["Get"+suffix] = true
+				genFirst("Get"+suffix, cols)
+			}
+		case IndexSimple, IndexFK:
+			if !generated["List"+suffix] {
+				generated["List"+suffix] = true
+				genList("List"+suffix, cols)
+			}
+		}
+	}
+
+	// list
+	w.FL("func (r %s) List(ctx context.Context, scopes ...%s) ([]*%s, error) {", repo, scopeTyp, model)
+	w.I(1).FL("var rows []*%s", model)
+	if isGorm {
+		w.I(1).L("if err := r.DB.WithContext(ctx).Scopes(scopes...).Find(&rows).Error; err != nil {")
+	} else {
+		w.I(1).L("q := r.DB.NewSelect().Model(&rows)")
+		w.I(1).L("for _, scope := range scopes {")
+		w.I(2).L("q = q.Apply(scope)")
+		w.I(1).L("}")
+		w.I(1).L("if err := q.Scan(ctx); err != nil {")
+	}
+	w.I(2).L("return nil, sderr.WithStack(err)")
+	w.I(1).L("}")
+	w.I(1).L("return rows, nil")
+	w.L("}")
+	w.NL()
+
+	// find
+	w.FL("func (r %s) Find(ctx context.Context, p sdsql.Page, scopes ...%s) (*sdsql.PagingResult[*%s], error) {", repo, scopeTyp, model)
+	if isGorm {
+		w.I(1).FL("pr, err := sdgorm.FindPaging[*%s](func() *gorm.DB {", model)
+		w.I(2).FL("return r.DB.WithContext(ctx).Model(&%s{}).Scopes(scopes...)", model)
+		w.I(1).L("}, p)")
+	} else {
+		w.I(1).FL("pr, err := sdbun.SelectPage[*%s](ctx, r.DB, p, func(q *bun.SelectQuery) *bun.SelectQuery {", model)
+		w.I(2).L("for _, scope := range scopes {")
+		w.I(3).L("q = q.Apply(scope)")
+		w.I(2).L("}")
+		w.I(2).L("return q")
+		w.I(1).L("})")
+	}
+	w.I(1).L("if err != nil {")
+	w.I(2).L("return nil, sderr.WithStack(err)")
+	w.I(1).L("}")
+	w.I(1).L("return pr, nil")
+	w.L("}")
+	w.NL()
+
+	// first
+	if isGorm {
+		w.FL("func (r %s) first(ctx context.Context, conds map[string]any) (*%s, error) {", repo, model)
+		w.I(1).FL("row := new(%s)", model)
+		w.I(1).L("if err := r.DB.WithContext(ctx).Where(conds).Take(row).Error; err != nil {")
+	} else {
+		w.FL("func (r %s) first(ctx context.Context, scope func(*bun.SelectQuery) *bun.SelectQuery) (*%s, error) {", repo, model)
+		w.I(1).FL("row := new(%s)", model)
+		w.I(1).L("if err := r.DB.NewSelect().Model(row).Apply(scope).Limit(1).Scan(ctx); err != nil {")
+	}
+	w.I(2).L("return nil, sderr.WithStack(err)")
+	w.I(1).L("}")
+	w.I(1).L("return row, nil")
+	w.L("}")
+	w.NL()
+}
+
+func onCrudAPI(w sdcodegen.Writer, _ *Crud, _ *Blueprint, t Table) {
+	sdgengo.AddImportPackages(w, []string{
+		"github.com/gaorx/stardust5/sdecho",
+		"github.com/gaorx/stardust5/sderr",
+		"github.com/gaorx/stardust5/sdsql",
+		"github.com/labstack/echo/v4",
+	})
+	model := t.NameForGo()
+	idCol, _ := crudIdColumn(t)
+	idTyp := idCol.Type().String()
+	apiTyp := fmt.Sprintf("sdecho.CrudAPI[*%s, %s, *sdecho.AntdJsonRequest]", model, idTyp)
+	req := "*sdecho.AntdJsonRequest"
+	columnsVar := sdstrings.ToCamelL(model) + "UpdateColumns"
+
+	// columns
+	w.FL("// %s 更新时请求中的字段(json名、Go名或者列名)到列名的映射", columnsVar)
+	w.FL("var %s = map[string]string{", columnsVar)
+	var pairs [][2]string
+	for _, c := range t.Columns() {
+		if c.Id() == idCol.Id() {
+			continue
+		}
+		for _, k := range lo.Uniq([]string{c.NameForJson(), c.Id(), c.NameForDB()}) {
+			pairs = append(pairs, [2]string{strconv.Quote(k) + ":", strconv.Quote(c.NameForDB())})
+		}
+	}
+	keyWidth := lo.Max(lo.Map(pairs, func(p [2]string, _ int) int { return len(p[0]) }))
+	for _, p := range pairs {
+		w.I(1).FL("%-*s %s,", keyWidth, p[0], p[1])
+	}
+	w.L("}")
+	w.NL()
+
+	w.FL("// %sCrudAPI 使用%sRepo实现的CrudAPI，可以在返回值上设置Object、Batch等选项", model, model)
+	w.FL("func %sCrudAPI(repo %sRepo, path string) %s {", model, model, apiTyp)
+	w.I(1).FL("return %s{", apiTyp)
+	w.I(2).L("Path: path,")
+	w.I(2).FL("Create: func(ec echo.Context, o *%s, _ %s) (*%s, error) {", model, req, model)
+	w.I(3).L("if err := repo.Create(ec.Request().Context(), o); err != nil {")
+	w.I(4).L("return nil, err")
+	w.I(3).L("}")
+	w.I(3).L("return o, nil")
+	w.I(2).L("},")
+	w.I(2).FL("Update: func(ec echo.Context, o *%s, req %s) (*%s, error) {", model, req, model)
+	w.I(3).FL("if o.RecordID() == %s {", crudZeroOf(idTyp))
+	w.I(4).L("return nil, sderr.Wrap(sdecho.ErrBadRequest, \"no id\")")
+	w.I(3).L("}")
+	w.I(3).L("// 没有_fields时拒绝更新，避免请求中缺少的字段被零值覆盖")
+	w.I(3).L("if len(req.Fields()) <= 0 {")
+	w.I(4).L("return nil, sderr.Wrap(sdecho.ErrBadRequest, \"no _fields\")")
+	w.I(3).L("}")
+	w.I(3).L("var columns []string")
+	w.I(3).L("for _, field := range req.Fields() {")
+	w.I(4).FL("column, ok := %s[field]", columnsVar)
+	w.I(4).L("if !ok {")
+	w.I(5).L("return nil, sderr.Wrap(sdecho.ErrBadRequest, \"illegal field \"+field)")
+	w.I(4).L("}")
+	w.I(4).L("columns = append(columns, column)")
+	w.I(3).L("}")
+	w.I(3).L("ctx := ec.Request().Context()")
+	w.I(3).L("if err := repo.Update(ctx, o, columns...); err != nil {")
+	w.I(4).L("return nil, err")
+	w.I(3).L("}")
+	w.I(3).L("return repo.Get(ctx, o.RecordID())")
+	w.I(2).L("},")
+	w.I(2).FL("Delete: func(ec echo.Context, id %s, _ %s) error {", idTyp, req)
+	w.I(3).L("return repo.Delete(ec.Request().Context(), id)")
+	w.I(2).L("},")
+	w.I(2).FL("Get: func(ec echo.Context, id %s, _ %s) (*%s, error) {", idTyp, req, model)
+	w.I(3).L("return repo.Get(ec.Request().Context(), id)")
+	w.I(2).L("},")
+	w.I(2).FL("Find: func(ec echo.Context, req %s) (*sdecho.FindResult[*%s], error) {", req, model)
+	w.I(3).L("page := req.Page()")
+	w.I(3).L("pr, err := repo.Find(ec.Request().Context(), sdsql.Page1(page.Page, page.PageSize).WithDefaultSize(20))")
+	w.I(3).L("if err != nil {")
+	w.I(4).L("return nil, err")
+	w.I(3).L("}")
+	w.I(3).FL("return &sdecho.FindResult[*%s]{", model)
+	w.I(4).L("Data:      pr.Rows,")
+	w.I(4).L("Request:   req,")
+	w.I(4).L("NumRows:   pr.NumRows,")
+	w.I(4).L("PageSize:  pr.PageSize,")
+	w.I(4).L("PageNum:   pr.PageNum,")
+	w.I(4).L("PageTotal: pr.PageTotal,")
+	w.I(3).L("}, nil")
+	w.I(2).L("},")
+	if t.Comment() != "" {
+		w.I(2).FL("Summary: %s,", strconv.Quote(t.Comment()))
+	}
+	w.I(1).L("}")
+	w.L("}")
+	w.NL()
+}
+
+// crudIdColumn 单列主键，并且类型满足sdecho.RecordID
+func crudIdColumn(t Table) (Column, bool) {
+	pk := t.PrimaryKey()
+	if pk == nil || len(pk.Columns()) != 1 {
+		return nil, false
+	}
+	c := t.Column(pk.Columns()[0])
+	if c == nil {
+		return nil, false
+	}
+	switch c.Type().String() {
+	case "string", "int", "int64":
+		return c, true
+	default:
+		return nil, false
+	}
+}
+
+func crudZeroOf(typ string) string {
+	if typ == "string" {
+		return `""`
+	}
+	return "0"
+}
+
+func crudParamNames(cols []Column) []string {
+	return lo.Map(cols, func(c Column, _ int) string {
+		name := sdstrings.ToCamelL(c.Id())
+		if token.IsKeyword(name) || lo.Contains([]string{"ctx", "r", "q", "tx"}, name) {
+			name += "_"
+		}
+		return name
+	})
+}
+
+func crudColumnGoType(w sdcodegen.Writer, c Column) string {
+	goTyp := getMemberGoType(c.Type(), "", "")
+	sdgengo.AddImportPackages(w, goTyp.pkgPaths)
+	return goTyp.typ
+}
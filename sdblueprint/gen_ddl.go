@@ -0,0 +1,217 @@
+package sdblueprint
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/samber/lo"
+	"slices"
+	"strings"
+)
+
+// ddlTask MysqlDDL、PostgresDDL和SqliteDDL共用的生成流程
+type ddlTask struct {
+	dialect       string
+	tableIds      []string
+	fileForCreate string
+	fileForDrop   string
+	withDrop      bool
+	withoutCreate bool
+	onHeader      func(w sdcodegen.Writer)
+	onCreate      func(w sdcodegen.Writer, t Table)
+	onDrop        func(w sdcodegen.Writer, t Table)
+}
+
+func generateDDLTo(buffs *sdcodegen.Buffers, bp *Blueprint, task ddlTask) error {
+	tableIds := matchIds(bp.TableIds(), task.tableIds)
+	if len(tableIds) <= 0 {
+		return nil
+	}
+
+	// filename
+	if !task.withoutCreate {
+		if task.fileForCreate == "" {
+			return sderr.New("no filename on generate DDL for create tables")
+		}
+	}
+	if task.withDrop {
+		if task.fileForDrop == "" {
+			task.fileForDrop = task.fileForCreate
+		}
+		if task.fileForDrop == "" {
+			return sderr.New("no filename on generate DDL for drop tables")
+		}
+	}
+
+	errMsg := fmt.Sprintf("blueprint generate %s DDL error", task.dialect)
+	appendBuff := func(file string, t Table) (*sdcodegen.Buffer, error) {
+		filename, err := executeTemplate(file, map[string]any{"Id": t.Id()})
+		if err != nil {
+			return nil, sderr.WithStack(err)
+		}
+		buff := buffs.Append(filename)
+		if buff.IsEmpty() {
+			if ok := lo.Try0(func() {
+				task.onHeader(buff)
+			}); !ok {
+				return nil, sderr.NewWith(errMsg, "on_header")
+			}
+		}
+		return buff, nil
+	}
+
+	// drop table
+	if task.withDrop {
+		for i, tableId := range lo.Reverse(slices.Clone(tableIds)) {
+			t := bp.Table(tableId)
+			if t == nil {
+				panic(sderr.NewWith("not found table", tableId))
+			}
+			buff, err := appendBuff(task.fileForDrop, t)
+			if err != nil {
+				return err
+			}
+			if ok := lo.Try0(func() {
+				task.onDrop(buff, t)
+			}); !ok {
+				return sderr.NewWith(errMsg, "on_drop")
+			}
+			if i >= len(tableIds)-1 {
+				buff.NL()
+			}
+		}
+	}
+
+	// create table
+	if !task.withoutCreate {
+		for _, tableId := range tableIds {
+			t := bp.Table(tableId)
+			if t == nil {
+				panic(sderr.NewWith("not found table", tableId))
+			}
+			buff, err := appendBuff(task.fileForCreate, t)
+			if err != nil {
+				return err
+			}
+			if ok := lo.Try0(func() {
+				task.onCreate(buff, t)
+			}); !ok {
+				return sderr.NewWith(errMsg, "on_create")
+			}
+		}
+	}
+	return nil
+}
+
+func onDDLHeader(w sdcodegen.Writer) {
+	w.NL()
+	w.L("-- AUTO GENERATED, DO NOT EDIT")
+	w.L("-- AUTO GENERATED, DO NOT EDIT")
+	w.L("-- AUTO GENERATED, DO NOT EDIT")
+	w.NL()
+}
+
+func ddlColumnNames(t Table, colNames []string) []string {
+	return lo.Map(colNames, func(colName string, _ int) string {
+		return t.Column(colName).NameForDB()
+	})
+}
+
+func ddlQuoteList(names []string, q func(string) string) string {
+	return strings.Join(lo.Map(names, func(name string, _ int) string {
+		return q(name)
+	}), ",")
+}
+
+// ddlIndexName 生成没有名称的索引的名称，postgres和sqlite中索引名称在整个schema中唯一，所以带上表名
+func ddlIndexName(t Table, idx Index, prefix string) string {
+	if idx.Name() != "" {
+		return idx.Name()
+	}
+	return prefix + "_" + t.NameForDB() + "_" + strings.Join(ddlColumnNames(t, idx.Columns()), "_")
+}
+
+// ddlIndexColumns 索引的列，IndexDESC时每列都加上DESC
+func ddlIndexColumns(t Table, idx Index, q func(string) string) string {
+	return strings.Join(lo.Map(ddlColumnNames(t, idx.Columns()), func(name string, _ int) string {
+		if idx.Order() == IndexDESC {
+			return q(name) + " DESC"
+		}
+		return q(name)
+	}), ",")
+}
+
+func ddlSingleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// mysqlTypeOf 拆分mysql的类型，例如 VARCHAR(32) -> VARCHAR, (32)；INT(11) UNSIGNED -> INT, (11), unsigned
+func mysqlTypeOf(typ string) (string, string, bool) {
+	typ = strings.ToUpper(strings.TrimSpace(typ))
+	unsigned := strings.Contains(typ, "UNSIGNED")
+	typ = strings.TrimSpace(strings.NewReplacer("UNSIGNED", "", "ZEROFILL", "").Replace(typ))
+	if i := strings.Index(typ, "("); i >= 0 {
+		return strings.TrimSpace(typ[:i]), typ[i:], unsigned
+	}
+	return typ, "", unsigned
+}
+
+// mysqlTypeToPostgres 将db_type中已知的mysql类型转换为postgres的类型
+func mysqlTypeToPostgres(typ string) (string, bool) {
+	base, args, unsigned := mysqlTypeOf(typ)
+	switch base {
+	case "TINYINT":
+		return "SMALLINT", true
+	case "SMALLINT":
+		return lo.Ternary(unsigned, "INTEGER", "SMALLINT"), true
+	case "MEDIUMINT", "INT", "INTEGER":
+		return lo.Ternary(unsigned, "BIGINT", "INTEGER"), true
+	case "BIGINT":
+		return lo.Ternary(unsigned, "NUMERIC(20)", "BIGINT"), true
+	case "BOOL", "BOOLEAN":
+		return "BOOLEAN", true
+	case "FLOAT":
+		return "REAL", true
+	case "DOUBLE", "DOUBLE PRECISION", "REAL":
+		return "DOUBLE PRECISION", true
+	case "DECIMAL", "NUMERIC":
+		return "NUMERIC" + args, true
+	case "CHAR", "VARCHAR":
+		return base + args, true
+	case "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT":
+		return "TEXT", true
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB":
+		return "BYTEA", true
+	case "DATETIME", "TIMESTAMP":
+		return "TIMESTAMP" + args, true
+	case "DATE":
+		return "DATE", true
+	case "TIME":
+		return "TIME" + args, true
+	case "JSON":
+		return "JSONB", true
+	default:
+		return "", false
+	}
+}
+
+// mysqlTypeToSqlite 将db_type中已知的mysql类型转换为sqlite的类型
+func mysqlTypeToSqlite(typ string) (string, bool) {
+	base, args, _ := mysqlTypeOf(typ)
+	switch base {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "BOOL", "BOOLEAN":
+		return "INTEGER", true
+	case "FLOAT", "DOUBLE", "DOUBLE PRECISION", "REAL":
+		return "REAL", true
+	case "DECIMAL", "NUMERIC":
+		return "NUMERIC" + args, true
+	case "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT", "JSON":
+		return "TEXT", true
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB":
+		return "BLOB", true
+	case "DATETIME", "TIMESTAMP", "DATE", "TIME":
+		return base, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,51 @@
+package sdblueprint
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMysqlTypeToOthers(t *testing.T) {
+	for _, c := range []struct {
+		mysql, postgres, sqlite string
+	}{
+		{"VARCHAR(32)", "VARCHAR(32)", "TEXT"},
+		{"int(11) unsigned", "BIGINT", "INTEGER"},
+		{"BIGINT UNSIGNED", "NUMERIC(20)", "INTEGER"},
+		{"TINYINT", "SMALLINT", "INTEGER"},
+		{"DECIMAL(10,2)", "NUMERIC(10,2)", "NUMERIC(10,2)"},
+		{"DATETIME", "TIMESTAMP", "DATETIME"},
+		{"DATETIME(3)", "TIMESTAMP(3)", "DATETIME"},
+		{"MEDIUMBLOB", "BYTEA", "BLOB"},
+		{"LONGTEXT", "TEXT", "TEXT"},
+		{"JSON", "JSONB", "TEXT"},
+	} {
+		typ, ok := mysqlTypeToPostgres(c.mysql)
+		assert.True(t, ok, c.mysql)
+		assert.Equal(t, c.postgres, typ, c.mysql)
+		typ, ok = mysqlTypeToSqlite(c.mysql)
+		assert.True(t, ok, c.mysql)
+		assert.Equal(t, c.sqlite, typ, c.mysql)
+	}
+	_, ok := mysqlTypeToPostgres("ENUM('a','b')")
+	assert.False(t, ok)
+	_, ok = mysqlTypeToSqlite("GEOMETRY")
+	assert.False(t, ok)
+}
+
+func TestPostgresDefaultLiteral(t *testing.T) {
+	for _, c := range []struct {
+		typ      string
+		v        any
+		expected string
+	}{
+		{"BOOLEAN", true, "true"},
+		{"bool", false, "false"},
+		{"SMALLINT", true, "1"},
+		{"SMALLINT", false, "0"},
+		{"INTEGER", int64(3), "3"},
+		{"VARCHAR(32)", "it's", "'it''s'"},
+	} {
+		assert.Equal(t, c.expected, postgresDefaultLiteral(c.typ, c.v), c.typ)
+	}
+}
@@ -32,7 +32,8 @@ type GormModel struct {
 	OnQuery  func(w sdcodegen.Writer, g *GormModel, bp *Blueprint, q Query)
 
 	// options
-	Package string
+	Package        string
+	CheckSQLSyntax bool // 使用sdsqlparser(mysql语法)检查query中的SQL语法，sdsqlparser不支持其他方言所以默认关闭，ForModule中只生成mysql DDL的module会开启
 }
 
 var _ Generator = GormModel{}
@@ -107,6 +108,9 @@ func (g GormModel) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
 		if q == nil {
 			panic(sderr.NewWith("not found query", queryId))
 		}
+		if err := checkQuerySQL(q, g.CheckSQLSyntax); err != nil {
+			return err
+		}
 		filename, err := executeTemplate(g.FileForQuery, map[string]any{"Id": q.Id()})
 		if err != nil {
 			return sderr.WithStack(err)
@@ -306,19 +310,19 @@ func onGormQuery(w sdcodegen.Writer, g *GormModel, _ *Blueprint, q Query) {
 	case QueryForExec:
 		if !q.ReturnRowAffected() {
 			sdgengo.Func(w, q.Id(), getGenParams(), sdgengo.Return("error"), func(w sdcodegen.Writer) {
-				w.I(1).FL(withContext("dbr := tx.Exec(%s%s)"), strconv.Quote(q.SQL()), joinNamedParams())
+				w.I(1).FL(withContext("dbr := tx.Exec(%s%s)"), strconv.Quote(gormQuerySQL(q)), joinNamedParams())
 				genDbrErr(w, "dbr", false)
 			}).NL()
 		} else {
 			sdgengo.Func(w, q.Id(), getGenParams(), sdgengo.Return("int64", "error"), func(w sdcodegen.Writer) {
-				w.I(1).FL(withContext("dbr := tx.Exec(%s%s)"), strconv.Quote(q.SQL()), joinNamedParams())
+				w.I(1).FL(withContext("dbr := tx.Exec(%s%s)"), strconv.Quote(gormQuerySQL(q)), joinNamedParams())
 				genDbrErr(w, "dbr", true)
 			}).NL()
 		}
 	case QueryForRecord:
 		sdgengo.Func(w, q.Id(), getGenParams(), sdgengo.Return(q.Result().Type(), "error"), func(w sdcodegen.Writer) {
 			w.I(1).FL("var row %s", q.Result().Type())
-			w.I(1).FL(withContext("dbr := tx.Raw(%s%s).Take(&row)"), strconv.Quote(q.SQL()), joinNamedParams())
+			w.I(1).FL(withContext("dbr := tx.Raw(%s%s).Take(&row)"), strconv.Quote(gormQuerySQL(q)), joinNamedParams())
 			w.I(1).FL("if dbr.Error != nil {")
 			w.I(2).FL("return nil, sderr.WithStack(dbr.Error)")
 			w.I(1).FL("}")
@@ -327,7 +331,7 @@ func onGormQuery(w sdcodegen.Writer, g *GormModel, _ *Blueprint, q Query) {
 	case QueryForRecords:
 		sdgengo.Func(w, q.Id(), getGenParams(), sdgengo.Return(q.Result().Type(), "error"), func(w sdcodegen.Writer) {
 			w.I(1).FL("var rows %s", q.Result().Type())
-			w.I(1).FL(withContext("dbr := tx.Raw(%s%s).Find(&rows)"), strconv.Quote(q.SQL()), joinNamedParams())
+			w.I(1).FL(withContext("dbr := tx.Raw(%s%s).Find(&rows)"), strconv.Quote(gormQuerySQL(q)), joinNamedParams())
 			w.I(1).FL("if dbr.Error != nil {")
 			w.I(2).FL("return nil, sderr.WithStack(dbr.Error)")
 			w.I(1).FL("}")
@@ -336,7 +340,7 @@ func onGormQuery(w sdcodegen.Writer, g *GormModel, _ *Blueprint, q Query) {
 	case QueryForScalar:
 		sdgengo.Func(w, q.Id(), getGenParams(), sdgengo.Return(q.Result().Type(), "error"), func(w sdcodegen.Writer) {
 			w.I(1).FL("var r %s", q.Result().Type())
-			w.I(1).FL(withContext("dbr := tx.Raw(%s%s).Scan(&r)"), strconv.Quote(q.SQL()), joinNamedParams())
+			w.I(1).FL(withContext("dbr := tx.Raw(%s%s).Scan(&r)"), strconv.Quote(gormQuerySQL(q)), joinNamedParams())
 			w.I(1).FL("if dbr.Error != nil {")
 			w.I(2).FL("return r, sderr.WithStack(dbr.Error)")
 			w.I(1).FL("}")
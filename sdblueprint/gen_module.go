@@ -24,6 +24,7 @@ func (g ForModule) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
 		if m == nil {
 			panic(sderr.NewWith("not found module", moduleId))
 		}
+		checkSQLSyntax := isMysqlModule(m)
 		for _, t := range m.Tasks() {
 			switch t1 := t.(type) {
 			case *ModuleTaskGenerateSkeleton:
@@ -45,6 +46,7 @@ func (g ForModule) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
 					FileForModel:     filepath.Join(dirname, "models.gen.go"),
 					WithQuery:        true,
 					QueryWithContext: t1.QueryWithContext,
+					CheckSQLSyntax:   checkSQLSyntax,
 				}).GenerateTo(buffs, getSub(bp, t1.Groups)); err != nil {
 					return sderr.WithStack(err)
 				}
@@ -54,7 +56,9 @@ func (g ForModule) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
 					return sderr.NewWith("no dir in generate BUN model task", m.Id())
 				}
 				if err := (BunModel{
-					FileForModel: filepath.Join(dirname, "models.gen.go"),
+					FileForModel:   filepath.Join(dirname, "models.gen.go"),
+					WithQuery:      true,
+					CheckSQLSyntax: checkSQLSyntax,
 				}).GenerateTo(buffs, getSub(bp, t1.Groups)); err != nil {
 					return sderr.WithStack(err)
 				}
@@ -70,6 +74,57 @@ func (g ForModule) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
 				}).GenerateTo(buffs, getSub(bp, t1.Groups)); err != nil {
 					return sderr.WithStack(err)
 				}
+			case *ModuleTaskGeneratePostgresDDL:
+				dirname := t1.Dirname
+				if dirname == "" {
+					return sderr.NewWith("no dir in generate POSTGRES ddl task", m.Id())
+				}
+				if err := (PostgresDDL{
+					FileForCreate: filepath.Join(dirname, "create_table.gen.sql"),
+					FileForDrop:   filepath.Join(dirname, "drop_table.gen.sql"),
+					WithDrop:      true,
+					Schema:        t1.Schema,
+				}).GenerateTo(buffs, getSub(bp, t1.Groups)); err != nil {
+					return sderr.WithStack(err)
+				}
+			case *ModuleTaskGenerateSqliteDDL:
+				dirname := t1.Dirname
+				if dirname == "" {
+					return sderr.NewWith("no dir in generate SQLITE ddl task", m.Id())
+				}
+				if err := (SqliteDDL{
+					FileForCreate: filepath.Join(dirname, "create_table.gen.sql"),
+					FileForDrop:   filepath.Join(dirname, "drop_table.gen.sql"),
+					WithDrop:      true,
+				}).GenerateTo(buffs, getSub(bp, t1.Groups)); err != nil {
+					return sderr.WithStack(err)
+				}
+			case *ModuleTaskGenerateCrud:
+				dirname := t1.Dirname
+				if dirname == "" {
+					return sderr.NewWith("no dir in generate CRUD task", m.Id())
+				}
+				g := Crud{
+					FileForRepo: filepath.Join(dirname, "crud.gen.go"),
+					Driver:      t1.Driver,
+				}
+				if t1.WithAPI {
+					g.FileForAPI = filepath.Join(dirname, "crud_api.gen.go")
+				}
+				if err := g.GenerateTo(buffs, getSub(bp, t1.Groups)); err != nil {
+					return sderr.WithStack(err)
+				}
+			case *ModuleTaskGenerateTypeScriptModel:
+				dirname := t1.Dirname
+				if dirname == "" {
+					return sderr.NewWith("no dir in generate TypeScript model task", m.Id())
+				}
+				if err := (TypeScriptModel{
+					FileForModel: filepath.Join(dirname, "models.gen.ts"),
+					WithColumns:  t1.WithColumns,
+				}).GenerateTo(buffs, getSub(bp, t1.Groups)); err != nil {
+					return sderr.WithStack(err)
+				}
 			default:
 				panic(sderr.NewWith("illegal task", sderr.Attrs{"task": reflect.TypeOf(t1).String(), "model": m.Id()}))
 			}
@@ -112,3 +167,17 @@ func getSub(bp *Blueprint, groups []string) *Blueprint {
 	}
 	return bp.Sub(groups...)
 }
+
+// isMysqlModule module只生成mysql的DDL时，其中的query按照mysql语法检查
+func isMysqlModule(m Module) bool {
+	mysql := false
+	for _, t := range m.Tasks() {
+		switch t.(type) {
+		case *ModuleTaskGenerateMysqlDDL:
+			mysql = true
+		case *ModuleTaskGeneratePostgresDDL, *ModuleTaskGenerateSqliteDDL:
+			return false
+		}
+	}
+	return mysql
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/gaorx/stardust5/sdcodegen"
 	"github.com/gaorx/stardust5/sderr"
 	"github.com/samber/lo"
-	"slices"
 	"strings"
 	"unicode"
 )
@@ -32,26 +31,6 @@ type MysqlDDL struct {
 var _ Generator = MysqlDDL{}
 
 func (g MysqlDDL) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
-	tableIds := matchIds(bp.TableIds(), g.TableIds)
-	if len(tableIds) <= 0 {
-		return nil
-	}
-
-	// filename
-	if !g.WithoutCreate {
-		if g.FileForCreate == "" {
-			return sderr.New("no filename on generate DDL for create tables")
-		}
-	}
-	if g.WithDrop {
-		if g.FileForDrop == "" {
-			g.FileForDrop = g.FileForCreate
-		}
-		if g.FileForDrop == "" {
-			return sderr.New("no filename on generate DDL for drop tables")
-		}
-	}
-
 	// callbacks
 	if g.OnHeader == nil {
 		g.OnHeader = onMysqlHeader
@@ -75,71 +54,21 @@ func (g MysqlDDL) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
 		g.Engine = "InnoDB"
 	}
 
-	// drop table
-	if g.WithDrop {
-		for i, tableId := range lo.Reverse(slices.Clone(tableIds)) {
-			t := bp.Table(tableId)
-			if t == nil {
-				panic(sderr.NewWith("not found table", t.Id()))
-			}
-			filename, err := executeTemplate(g.FileForDrop, map[string]any{"Id": t.Id()})
-			if err != nil {
-				return sderr.WithStack(err)
-			}
-			buff := buffs.Append(filename)
-			if buff.IsEmpty() {
-				if ok := lo.Try0(func() {
-					g.OnHeader(buff, &g, bp)
-				}); !ok {
-					return sderr.NewWith("blueprint generate mysql DDL error", "on_header")
-				}
-			}
-			if ok := lo.Try0(func() {
-				g.OnDrop(buff, &g, bp, t)
-			}); !ok {
-				return sderr.NewWith("blueprint generate mysql DDL error", "on_drop")
-			}
-			if i >= len(tableIds)-1 {
-				buff.NL()
-			}
-		}
-	}
-
-	// create table
-	if !g.WithoutCreate {
-		for _, tableId := range tableIds {
-			t := bp.Table(tableId)
-			if t == nil {
-				panic(sderr.NewWith("not found table", t.Id()))
-			}
-			filename, err := executeTemplate(g.FileForCreate, map[string]any{"Id": t.Id()})
-			if err != nil {
-				return sderr.WithStack(err)
-			}
-			buff := buffs.Append(filename)
-			if buff.IsEmpty() {
-				if ok := lo.Try0(func() {
-					g.OnHeader(buff, &g, bp)
-				}); !ok {
-					return sderr.NewWith("blueprint generate mysql DDL error", "on_header")
-				}
-			}
-			if ok := lo.Try0(func() {
-				g.OnCreate(buff, &g, bp, t)
-			}); !ok {
-				return sderr.NewWith("blueprint generate mysql DDL error", "on_create")
-			}
-		}
-	}
-	return nil
+	return generateDDLTo(buffs, bp, ddlTask{
+		dialect:       "mysql",
+		tableIds:      g.TableIds,
+		fileForCreate: g.FileForCreate,
+		fileForDrop:   g.FileForDrop,
+		withDrop:      g.WithDrop,
+		withoutCreate: g.WithoutCreate,
+		onHeader:      func(w sdcodegen.Writer) { g.OnHeader(w, &g, bp) },
+		onCreate:      func(w sdcodegen.Writer, t Table) { g.OnCreate(w, &g, bp, t) },
+		onDrop:        func(w sdcodegen.Writer, t Table) { g.OnDrop(w, &g, bp, t) },
+	})
 }
 
 func onMysqlHeader(w sdcodegen.Writer, _ *MysqlDDL, _ *Blueprint) {
-	w.NL()
-	w.L("-- AUTO GENERATED, DO NOT EDIT")
-	w.L("-- AUTO GENERATED, DO NOT EDIT")
-	w.L("-- AUTO GENERATED, DO NOT EDIT")
-	w.NL()
+	onDDLHeader(w)
 }
 
 func onMysqlCreate(b sdcodegen.Writer, g *MysqlDDL, bp *Blueprint, t Table) {
@@ -256,7 +185,7 @@ func mysqlDataTypeOf(c Column) string {
 		return "VARCHAR(255)"
 	case "bool":
 		return "TINYINT"
-	case "[]byte":
+	case "[]byte", "[]uint8":
 		return "MEDIUMBLOB"
 	case "int", "int8", "int16", "int32":
 		return "INT"
@@ -0,0 +1,196 @@
+package sdblueprint
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/samber/lo"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type PostgresDDL struct {
+	TableIds      []string
+	FileForCreate string
+	FileForDrop   string
+
+	// callbacks
+	OnHeader func(w sdcodegen.Writer, g *PostgresDDL, bp *Blueprint)
+	OnCreate func(w sdcodegen.Writer, g *PostgresDDL, bp *Blueprint, t Table)
+	OnDrop   func(w sdcodegen.Writer, g *PostgresDDL, bp *Blueprint, t Table)
+
+	// options
+	Schema        string // 为空时不指定schema
+	DisableFK     bool
+	WithDrop      bool
+	WithoutCreate bool
+}
+
+var _ Generator = PostgresDDL{}
+
+func (g PostgresDDL) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
+	// callbacks
+	if g.OnHeader == nil {
+		g.OnHeader = onPostgresHeader
+	}
+	if g.OnCreate == nil {
+		g.OnCreate = onPostgresCreate
+	}
+	if g.OnDrop == nil {
+		g.OnDrop = onPostgresDrop
+	}
+
+	return generateDDLTo(buffs, bp, ddlTask{
+		dialect:       "postgres",
+		tableIds:      g.TableIds,
+		fileForCreate: g.FileForCreate,
+		fileForDrop:   g.FileForDrop,
+		withDrop:      g.WithDrop,
+		withoutCreate: g.WithoutCreate,
+		onHeader:      func(w sdcodegen.Writer) { g.OnHeader(w, &g, bp) },
+		onCreate:      func(w sdcodegen.Writer, t Table) { g.OnCreate(w, &g, bp, t) },
+		onDrop:        func(w sdcodegen.Writer, t Table) { g.OnDrop(w, &g, bp, t) },
+	})
+}
+
+func onPostgresHeader(w sdcodegen.Writer, _ *PostgresDDL, _ *Blueprint) {
+	onDDLHeader(w)
+}
+
+func onPostgresCreate(b sdcodegen.Writer, g *PostgresDDL, bp *Blueprint, t Table) {
+	q := postgresQuote
+	qt := func(t Table) string {
+		return g.qualify(t.NameForDB())
+	}
+
+	b.FL("-- %s", t.Id())
+	b.FL("CREATE TABLE IF NOT EXISTS %s (", qt(t))
+	for _, c := range t.Columns() {
+		b.I(1)
+		b.F("%s %s", q(c.NameForDB()), postgresDataTypeOf(c))
+		b.If(c.IsAutoIncrement(), " GENERATED BY DEFAULT AS IDENTITY")
+		b.If(!c.IsAllowNull(), " NOT NULL")
+		// identity列不能同时有DEFAULT
+		if c.Default() != nil && !c.IsAutoIncrement() {
+			b.F(" DEFAULT %s", postgresDefaultLiteral(postgresDataTypeOf(c), c.Default()))
+		}
+		b.P(",")
+		b.NL()
+	}
+	b.NL()
+	for _, idx := range t.Indexes() {
+		switch idx.Kind() {
+		case IndexPK:
+			b.I(1).F("PRIMARY KEY (%s)", ddlQuoteList(ddlColumnNames(t, idx.Columns()), q)).P(",").NL()
+		case IndexFK:
+			if !g.DisableFK {
+				refTable := bp.Table(idx.ReferenceTable())
+				if refTable == nil {
+					panic(sderr.NewWith("not found foreign key reference table in query", idx.ReferenceTable()))
+				}
+				b.I(1)
+				if idx.Name() != "" {
+					b.F("CONSTRAINT %s ", q(idx.Name()))
+				}
+				b.F("FOREIGN KEY (%s) REFERENCES %s (%s)",
+					ddlQuoteList(ddlColumnNames(t, idx.Columns()), q),
+					qt(refTable),
+					ddlQuoteList(ddlColumnNames(refTable, idx.ReferenceColumns()), q),
+				)
+				b.P(",").NL()
+			}
+		}
+	}
+	b.Modify(func(code string) string {
+		code = strings.TrimRightFunc(code, func(c rune) bool {
+			return unicode.IsSpace(c)
+		})
+		code = strings.TrimSuffix(code, ",")
+		return code
+	})
+	b.NL()
+	b.L(");")
+
+	// 索引和注释在postgres中需要单独的语句
+	for _, idx := range t.Indexes() {
+		switch idx.Kind() {
+		case IndexSimple:
+			b.FL("CREATE INDEX IF NOT EXISTS %s ON %s (%s);", q(ddlIndexName(t, idx, "idx")), qt(t), ddlIndexColumns(t, idx, q))
+		case IndexUnique:
+			b.FL("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s);", q(ddlIndexName(t, idx, "uk")), qt(t), ddlIndexColumns(t, idx, q))
+		}
+	}
+	if t.Comment() != "" {
+		b.FL("COMMENT ON TABLE %s IS %s;", qt(t), ddlSingleQuote(t.Comment()))
+	}
+	for _, c := range t.Columns() {
+		if c.Comment() != "" {
+			b.FL("COMMENT ON COLUMN %s.%s IS %s;", qt(t), q(c.NameForDB()), ddlSingleQuote(c.Comment()))
+		}
+	}
+	b.NL()
+}
+
+func onPostgresDrop(w sdcodegen.Writer, g *PostgresDDL, _ *Blueprint, t Table) {
+	w.FL("DROP TABLE IF EXISTS %s CASCADE;", g.qualify(t.NameForDB()))
+}
+
+func (g *PostgresDDL) qualify(name string) string {
+	if g.Schema != "" {
+		return postgresQuote(g.Schema) + "." + postgresQuote(name)
+	}
+	return postgresQuote(name)
+}
+
+func postgresQuote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// postgresDefaultLiteral 根据最终的SQL类型生成默认值，bool只有BOOLEAN列使用true/false，整数列(例如db_type为TINYINT(1))使用1/0
+func postgresDefaultLiteral(sqlType string, v any) string {
+	switch v1 := v.(type) {
+	case string:
+		return ddlSingleQuote(v1)
+	case bool:
+		if normalizeColumnType(DialectPostgres, sqlType) == "boolean" {
+			return strconv.FormatBool(v1)
+		}
+		return lo.Ternary(v1, "1", "0")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// postgresDataTypeOf 优先使用postgres_type，db_type/dbtype是mysql的类型，只转换已知的类型
+func postgresDataTypeOf(c Column) string {
+	if dbTyp := c.Get("postgres_type").AsStr(); dbTyp != "" {
+		return dbTyp
+	}
+	if dbTyp := c.First([]string{"db_type", "dbtype"}).AsStr(); dbTyp != "" {
+		if typ, ok := mysqlTypeToPostgres(dbTyp); ok {
+			return typ
+		}
+		panic(sderr.NewWith("unknown db_type for POSTGRES, use postgres_type", dbTyp))
+	}
+	switch c.Type().String() {
+	case "string":
+		return "VARCHAR(255)"
+	case "bool":
+		return "BOOLEAN"
+	case "[]byte", "[]uint8":
+		return "BYTEA"
+	case "int8", "int16":
+		return "SMALLINT"
+	case "int", "int32", "uint8", "uint16":
+		return "INTEGER"
+	case "int64", "uint", "uint32":
+		return "BIGINT"
+	case "uint64":
+		return "NUMERIC(20)"
+	case "float32", "float64":
+		return "DOUBLE PRECISION"
+	default:
+		panic("illegal type for convert to POSTGRES data type")
+	}
+}
@@ -0,0 +1,179 @@
+package sdblueprint
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sderr"
+	"strings"
+	"unicode"
+)
+
+type SqliteDDL struct {
+	TableIds      []string
+	FileForCreate string
+	FileForDrop   string
+
+	// callbacks
+	OnHeader func(w sdcodegen.Writer, g *SqliteDDL, bp *Blueprint)
+	OnCreate func(w sdcodegen.Writer, g *SqliteDDL, bp *Blueprint, t Table)
+	OnDrop   func(w sdcodegen.Writer, g *SqliteDDL, bp *Blueprint, t Table)
+
+	// options
+	DisableFK     bool
+	WithDrop      bool
+	WithoutCreate bool
+}
+
+var _ Generator = SqliteDDL{}
+
+func (g SqliteDDL) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
+	// callbacks
+	if g.OnHeader == nil {
+		g.OnHeader = onSqliteHeader
+	}
+	if g.OnCreate == nil {
+		g.OnCreate = onSqliteCreate
+	}
+	if g.OnDrop == nil {
+		g.OnDrop = onSqliteDrop
+	}
+
+	return generateDDLTo(buffs, bp, ddlTask{
+		dialect:       "sqlite",
+		tableIds:      g.TableIds,
+		fileForCreate: g.FileForCreate,
+		fileForDrop:   g.FileForDrop,
+		withDrop:      g.WithDrop,
+		withoutCreate: g.WithoutCreate,
+		onHeader:      func(w sdcodegen.Writer) { g.OnHeader(w, &g, bp) },
+		onCreate:      func(w sdcodegen.Writer, t Table) { g.OnCreate(w, &g, bp, t) },
+		onDrop:        func(w sdcodegen.Writer, t Table) { g.OnDrop(w, &g, bp, t) },
+	})
+}
+
+func onSqliteHeader(w sdcodegen.Writer, _ *SqliteDDL, _ *Blueprint) {
+	onDDLHeader(w)
+}
+
+func onSqliteCreate(b sdcodegen.Writer, g *SqliteDDL, bp *Blueprint, t Table) {
+	q := sqliteQuote
+
+	defToStr := func(v any) string {
+		switch v1 := v.(type) {
+		case string:
+			return ddlSingleQuote(v1)
+		case bool:
+			if v1 {
+				return "1"
+			}
+			return "0"
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+
+	// sqlite的自增列必须是INTEGER PRIMARY KEY，此时不再单独声明主键
+	var autoIncrCol string
+	if pk := t.PrimaryKey(); pk != nil && len(pk.Columns()) == 1 {
+		if c := t.Column(pk.Columns()[0]); c != nil && c.IsAutoIncrement() {
+			autoIncrCol = c.Id()
+		}
+	}
+
+	b.FL("-- %s", t.Id())
+	b.FL("CREATE TABLE IF NOT EXISTS %s (", q(t.NameForDB()))
+	for _, c := range t.Columns() {
+		if c.Comment() != "" {
+			b.I(1).L("-- " + c.Comment())
+		}
+		b.I(1)
+		if c.Id() == autoIncrCol {
+			b.F("%s INTEGER PRIMARY KEY AUTOINCREMENT", q(c.NameForDB()))
+		} else {
+			b.F("%s %s", q(c.NameForDB()), sqliteDataTypeOf(c))
+			b.If(!c.IsAllowNull(), " NOT NULL")
+			if c.Default() != nil {
+				b.F(" DEFAULT %s", defToStr(c.Default()))
+			}
+		}
+		b.P(",")
+		b.NL()
+	}
+	b.NL()
+	for _, idx := range t.Indexes() {
+		switch idx.Kind() {
+		case IndexPK:
+			if autoIncrCol == "" {
+				b.I(1).F("PRIMARY KEY (%s)", ddlQuoteList(ddlColumnNames(t, idx.Columns()), q)).P(",").NL()
+			}
+		case IndexFK:
+			if !g.DisableFK {
+				refTable := bp.Table(idx.ReferenceTable())
+				if refTable == nil {
+					panic(sderr.NewWith("not found foreign key reference table in query", idx.ReferenceTable()))
+				}
+				b.I(1)
+				if idx.Name() != "" {
+					b.F("CONSTRAINT %s ", q(idx.Name()))
+				}
+				b.F("FOREIGN KEY (%s) REFERENCES %s (%s)",
+					ddlQuoteList(ddlColumnNames(t, idx.Columns()), q),
+					q(refTable.NameForDB()),
+					ddlQuoteList(ddlColumnNames(refTable, idx.ReferenceColumns()), q),
+				)
+				b.P(",").NL()
+			}
+		}
+	}
+	b.Modify(func(code string) string {
+		code = strings.TrimRightFunc(code, func(c rune) bool {
+			return unicode.IsSpace(c)
+		})
+		code = strings.TrimSuffix(code, ",")
+		return code
+	})
+	b.NL()
+	b.L(");")
+	for _, idx := range t.Indexes() {
+		switch idx.Kind() {
+		case IndexSimple:
+			b.FL("CREATE INDEX IF NOT EXISTS %s ON %s (%s);", q(ddlIndexName(t, idx, "idx")), q(t.NameForDB()), ddlIndexColumns(t, idx, q))
+		case IndexUnique:
+			b.FL("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s);", q(ddlIndexName(t, idx, "uk")), q(t.NameForDB()), ddlIndexColumns(t, idx, q))
+		}
+	}
+	b.NL()
+}
+
+func onSqliteDrop(w sdcodegen.Writer, _ *SqliteDDL, _ *Blueprint, t Table) {
+	w.FL("DROP TABLE IF EXISTS %s;", sqliteQuote(t.NameForDB()))
+}
+
+func sqliteQuote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqliteDataTypeOf 优先使用sqlite_type，db_type/dbtype是mysql的类型，只转换已知的类型
+func sqliteDataTypeOf(c Column) string {
+	if dbTyp := c.Get("sqlite_type").AsStr(); dbTyp != "" {
+		return dbTyp
+	}
+	if dbTyp := c.First([]string{"db_type", "dbtype"}).AsStr(); dbTyp != "" {
+		if typ, ok := mysqlTypeToSqlite(dbTyp); ok {
+			return typ
+		}
+		panic(sderr.NewWith("unknown db_type for SQLITE, use sqlite_type", dbTyp))
+	}
+	switch c.Type().String() {
+	case "string":
+		return "TEXT"
+	case "bool", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "INTEGER"
+	case "[]byte", "[]uint8":
+		return "BLOB"
+	case "float32", "float64":
+		return "REAL"
+	default:
+		panic("illegal type for convert to SQLITE data type")
+	}
+}
@@ -0,0 +1,256 @@
+package sdblueprint
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdstrings"
+	"github.com/samber/lo"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TypeScriptModel 为表生成TypeScript interface(字段名使用Column.NameForJson)，
+// 以及可选的Ant Design ProTable列定义，列定义中的title为列的注释，
+// 列上的enum属性(例如`enum:"1:启用,2:禁用"`)会生成为联合类型以及valueEnum，
+// json tag中有string选项的列生成为string，也可以使用ts_type属性直接指定TypeScript类型
+type TypeScriptModel struct {
+	TableIds     []string
+	FileForModel string
+	WithColumns  bool
+
+	// callback
+	OnHeader  func(w sdcodegen.Writer, g *TypeScriptModel, bp *Blueprint)
+	OnModel   func(w sdcodegen.Writer, g *TypeScriptModel, bp *Blueprint, t Table)
+	OnColumns func(w sdcodegen.Writer, g *TypeScriptModel, bp *Blueprint, t Table)
+}
+
+var _ Generator = TypeScriptModel{}
+
+func (g TypeScriptModel) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
+	tableIds := matchIds(bp.TableIds(), g.TableIds)
+	if len(tableIds) <= 0 {
+		return nil
+	}
+
+	// filename
+	if g.FileForModel == "" {
+		return sderr.New("no filename on generate typescript code for model")
+	}
+
+	// callback
+	if g.OnHeader == nil {
+		g.OnHeader = onTsHeader
+	}
+	if g.OnModel == nil {
+		g.OnModel = onTsModel
+	}
+	if g.OnColumns == nil {
+		g.OnColumns = onTsColumns
+	}
+
+	for _, tableId := range tableIds {
+		t := bp.Table(tableId)
+		if t == nil {
+			panic(sderr.NewWith("not found table", tableId))
+		}
+		filename, err := executeTemplate(g.FileForModel, map[string]any{"Id": t.Id()})
+		if err != nil {
+			return sderr.WithStack(err)
+		}
+		buff := buffs.Append(filename)
+		if buff.IsEmpty() {
+			if ok := lo.Try0(func() {
+				g.OnHeader(buff, &g, bp)
+			}); !ok {
+				return sderr.NewWith("blueprint generate TypeScript error", "on_header")
+			}
+		}
+		if ok := lo.Try0(func() {
+			g.OnModel(buff, &g, bp, t)
+		}); !ok {
+			return sderr.NewWith("blueprint generate TypeScript error", "on_model")
+		}
+		if g.WithColumns {
+			if ok := lo.Try0(func() {
+				g.OnColumns(buff, &g, bp, t)
+			}); !ok {
+				return sderr.NewWith("blueprint generate TypeScript error", "on_columns")
+			}
+		}
+	}
+	return nil
+}
+
+func onTsHeader(w sdcodegen.Writer, g *TypeScriptModel, _ *Blueprint) {
+	w.L("// AUTO GENERATED, DO NOT EDIT")
+	w.NL()
+	if g.WithColumns {
+		w.L("import type { ProColumns } from '@ant-design/pro-components';")
+		w.NL()
+	}
+}
+
+func onTsModel(w sdcodegen.Writer, _ *TypeScriptModel, _ *Blueprint, t Table) {
+	if t.Comment() != "" {
+		w.FL("/** %s */", t.Comment())
+	}
+	w.FL("export interface %s {", t.NameForGo())
+	for _, c := range tsColumns(t) {
+		if c.Comment() != "" {
+			w.I(1).FL("/** %s */", c.Comment())
+		}
+		optional := ""
+		if c.Type().Kind() == reflect.Pointer || tsJsonOption(c, "omitempty") {
+			optional = "?"
+		}
+		w.I(1).FL("%s%s: %s;", tsPropName(c.NameForJson()), optional, tsColumnType(c))
+	}
+	w.L("}")
+	w.NL()
+}
+
+func onTsColumns(w sdcodegen.Writer, _ *TypeScriptModel, _ *Blueprint, t Table) {
+	w.FL("export const %sColumns: ProColumns<%s>[] = [", sdstrings.ToCamelL(t.NameForGo()), t.NameForGo())
+	for _, c := range tsColumns(t) {
+		w.I(1).L("{")
+		w.I(2).FL("title: %s,", tsQuote(selectNotEmpty(c.Comment(), c.Id(), "")))
+		w.I(2).FL("dataIndex: %s,", tsQuote(c.NameForJson()))
+		w.I(2).FL("valueType: %s,", tsQuote(tsValueType(c)))
+		if enum := tsEnumOf(c); len(enum) > 0 {
+			w.I(2).L("valueEnum: {")
+			for _, item := range enum {
+				w.I(3).FL("%s: { text: %s },", tsEnumKey(c, item[0]), tsQuote(item[1]))
+			}
+			w.I(2).L("},")
+		}
+		if c.IsAutoIncrement() {
+			w.I(2).L("hideInForm: true,")
+			w.I(2).L("hideInSearch: true,")
+		}
+		w.I(1).L("},")
+	}
+	w.L("];")
+	w.NL()
+}
+
+// tsColumns 出现在JSON中的列
+func tsColumns(t Table) []Column {
+	return lo.Filter(t.Columns(), func(c Column, _ int) bool {
+		return c.NameForJson() != "-"
+	})
+}
+
+func tsJsonOption(c Column, opt string) bool {
+	l := strings.Split(c.Get("json").AsStr(), ",")
+	return lo.Contains(lo.Map(l[1:], func(s string, _ int) string { return strings.TrimSpace(s) }), opt)
+}
+
+// tsIsJsonString json tag中有string选项时，数字和布尔值在JSON中为字符串
+func tsIsJsonString(c Column) bool {
+	return tsJsonOption(c, "string")
+}
+
+func tsColumnType(c Column) string {
+	if typ := c.Get("ts_type").AsStr(); typ != "" {
+		return typ
+	}
+	if enum := tsEnumOf(c); len(enum) > 0 {
+		return strings.Join(lo.Map(enum, func(item [2]string, _ int) string {
+			return tsEnumKey(c, item[0])
+		}), " | ")
+	}
+	if tsIsJsonString(c) {
+		return "string"
+	}
+	return tsTypeOf(c.Type())
+}
+
+func tsTypeOf(typ reflect.Type) string {
+	switch typ {
+	case reflect.TypeOf(time.Time{}):
+		return "string"
+	case reflect.TypeOf([]byte(nil)):
+		return "string"
+	}
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return tsTypeOf(typ.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return tsTypeOf(typ.Elem()) + "[]"
+	case reflect.Map:
+		return fmt.Sprintf("Record<%s, %s>", tsTypeOf(typ.Key()), tsTypeOf(typ.Elem()))
+	default:
+		return "any"
+	}
+}
+
+// tsValueType ProTable的valueType
+func tsValueType(c Column) string {
+	if len(tsEnumOf(c)) > 0 {
+		return "select"
+	}
+	typ := c.Type()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "dateTime"
+	}
+	if tsIsJsonString(c) {
+		return "text"
+	}
+	switch tsTypeOf(typ) {
+	case "number":
+		return "digit"
+	case "boolean":
+		return "switch"
+	default:
+		return "text"
+	}
+}
+
+// tsEnumOf 解析enum属性，格式为`值:文本,值:文本`，省略文本时文本与值相同
+func tsEnumOf(c Column) [][2]string {
+	var enum [][2]string
+	for _, item := range sdstrings.SplitNonempty(c.Get("enum").AsStr(), ",", true) {
+		kv := strings.SplitN(item, ":", 2)
+		k := strings.TrimSpace(kv[0])
+		if len(kv) == 2 {
+			enum = append(enum, [2]string{k, strings.TrimSpace(kv[1])})
+		} else {
+			enum = append(enum, [2]string{k, k})
+		}
+	}
+	return enum
+}
+
+func tsEnumKey(c Column, k string) string {
+	if tsTypeOf(c.Type()) == "number" && !tsIsJsonString(c) {
+		return k
+	}
+	return tsQuote(k)
+}
+
+func tsPropName(name string) string {
+	for i, r := range name {
+		isAlpha := r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isAlpha && (i == 0 || r < '0' || r > '9') {
+			return tsQuote(name)
+		}
+	}
+	return name
+}
+
+func tsQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(s) + "'"
+}
@@ -23,27 +23,35 @@ type (
 )
 
 type (
-	MarkAsGenerateSkeleton  int
-	MarkAsGenerateGormModel int
-	MarkAsGenerateBunModel  int
-	MarkAsGenerateMysqlDDL  int
+	MarkAsGenerateSkeleton        int
+	MarkAsGenerateGormModel       int
+	MarkAsGenerateBunModel        int
+	MarkAsGenerateMysqlDDL        int
+	MarkAsGeneratePostgresDDL     int
+	MarkAsGenerateSqliteDDL       int
+	MarkAsGenerateCrud            int
+	MarkAsGenerateTypeScriptModel int
 )
 
 type markSet []reflect.Type
 
 var (
-	markAsTable             = sdreflect.T[MarkAsTable]()
-	markAsQuery             = sdreflect.T[MarkAsQuery]()
-	markAsModule            = sdreflect.T[MarkAsModule]()
-	markAsPrimaryKey        = sdreflect.T[MarkAsPrimaryKey]()
-	markAsUniqueIndex       = sdreflect.T[MarkAsUniqueIndex]()
-	markAsSimpleIndex       = sdreflect.T[MarkAsSimpleIndex]()
-	markAsForeignKey        = sdreflect.T[MarkAsForeignKey]()
-	markAsInlineQuery       = sdreflect.T[MarkAsInlineQuery]()
-	markAsGenerateSkeleton  = sdreflect.T[MarkAsGenerateSkeleton]()
-	markAsGenerateGormModel = sdreflect.T[MarkAsGenerateGormModel]()
-	markAsGenerateBunModel  = sdreflect.T[MarkAsGenerateBunModel]()
-	markAsGenerateMysqlDDL  = sdreflect.T[MarkAsGenerateMysqlDDL]()
+	markAsTable                   = sdreflect.T[MarkAsTable]()
+	markAsQuery                   = sdreflect.T[MarkAsQuery]()
+	markAsModule                  = sdreflect.T[MarkAsModule]()
+	markAsPrimaryKey              = sdreflect.T[MarkAsPrimaryKey]()
+	markAsUniqueIndex             = sdreflect.T[MarkAsUniqueIndex]()
+	markAsSimpleIndex             = sdreflect.T[MarkAsSimpleIndex]()
+	markAsForeignKey              = sdreflect.T[MarkAsForeignKey]()
+	markAsInlineQuery             = sdreflect.T[MarkAsInlineQuery]()
+	markAsGenerateSkeleton        = sdreflect.T[MarkAsGenerateSkeleton]()
+	markAsGenerateGormModel       = sdreflect.T[MarkAsGenerateGormModel]()
+	markAsGenerateBunModel        = sdreflect.T[MarkAsGenerateBunModel]()
+	markAsGenerateMysqlDDL        = sdreflect.T[MarkAsGenerateMysqlDDL]()
+	markAsGeneratePostgresDDL     = sdreflect.T[MarkAsGeneratePostgresDDL]()
+	markAsGenerateSqliteDDL       = sdreflect.T[MarkAsGenerateSqliteDDL]()
+	markAsGenerateCrud            = sdreflect.T[MarkAsGenerateCrud]()
+	markAsGenerateTypeScriptModel = sdreflect.T[MarkAsGenerateTypeScriptModel]()
 
 	// struct mark
 	structMarks = markSet{
@@ -68,6 +76,10 @@ var (
 		markAsGenerateGormModel,
 		markAsGenerateBunModel,
 		markAsGenerateMysqlDDL,
+		markAsGeneratePostgresDDL,
+		markAsGenerateSqliteDDL,
+		markAsGenerateCrud,
+		markAsGenerateTypeScriptModel,
 	}
 
 	// all
@@ -0,0 +1,333 @@
+package sdblueprint
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/samber/lo"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Migration 比较From(数据库或者之前快照的表结构)与Blueprint的差异，生成带版本号的up/down迁移脚本
+// 生成的文件为<Dirname>/<Version>_<Name>.up.sql和.down.sql，没有差异时不生成文件，已经存在的文件不会被覆盖
+// 删除表、删除列以及缩小列类型的语句默认被注释掉(down中对应的语句也被注释掉)，需要人工确认，AllowDestructive为true时直接生成
+type Migration struct {
+	From     *Schema
+	Dirname  string
+	Version  string // 默认为当前时间yyyymmddHHMMSS
+	Name     string // 默认为migrate
+	TableIds []string
+
+	// options
+	AllowDestructive bool
+}
+
+var _ Generator = Migration{}
+
+func (g Migration) GenerateTo(buffs *sdcodegen.Buffers, bp *Blueprint) error {
+	if g.From == nil {
+		return sderr.New("no schema for migration")
+	}
+	if g.Version == "" {
+		g.Version = time.Now().Format("20060102150405")
+	}
+	if g.Name == "" {
+		g.Name = "migrate"
+	}
+
+	to, err := SchemaOf(bp, g.From.Dialect)
+	if err != nil {
+		return sderr.WithStack(err)
+	}
+	from := g.From
+	if len(g.TableIds) > 0 {
+		// 只比较选中的表，避免删除其他的表
+		tableNames := lo.Map(matchIds(bp.TableIds(), g.TableIds), func(tableId string, _ int) string {
+			return bp.Table(tableId).NameForDB()
+		})
+		selected := func(t *SchemaTable, _ int) bool { return lo.Contains(tableNames, t.Name) }
+		to = &Schema{Dialect: to.Dialect, Tables: lo.Filter(to.Tables, selected)}
+		from = &Schema{Dialect: from.Dialect, Tables: lo.Filter(from.Tables, selected)}
+	}
+	steps := diffSchema(from, to)
+	if len(steps) <= 0 {
+		return nil
+	}
+
+	base := filepath.Join(g.Dirname, g.Version+"_"+g.Name)
+	buffs.Put(base+".up.sql", func(w sdcodegen.Writer) {
+		w.SetOverwrite(false)
+		onDDLHeader(w)
+		for _, step := range steps {
+			warning := ""
+			if step.destructive && !g.AllowDestructive {
+				warning = "destructive statements, uncomment them after confirmation"
+			}
+			writeMigrationStatements(w, step.up, warning)
+		}
+	})
+	buffs.Put(base+".down.sql", func(w sdcodegen.Writer) {
+		w.SetOverwrite(false)
+		onDDLHeader(w)
+		for _, step := range lo.Reverse(slices.Clone(steps)) {
+			// up中被注释的语句没有执行，对应的down语句也需要注释掉
+			warning := ""
+			if step.destructive && !g.AllowDestructive {
+				warning = "reverts commented destructive statements in up, uncomment them together"
+			}
+			writeMigrationStatements(w, step.down, warning)
+		}
+	})
+	return nil
+}
+
+// writeMigrationStatements warning不为空时注释掉所有语句，并在前面写入warning
+func writeMigrationStatements(w sdcodegen.Writer, stmts []string, warning string) {
+	if len(stmts) <= 0 {
+		return
+	}
+	commented := warning != ""
+	if commented {
+		w.L("-- WARNING: " + warning)
+	}
+	for _, stmt := range stmts {
+		for _, line := range strings.Split(stmt, "\n") {
+			w.If(commented, "-- ").L(line)
+		}
+	}
+	w.NL()
+}
+
+// diff
+
+type migrationStep struct {
+	up          []string
+	down        []string
+	destructive bool
+}
+
+// diffSchema up按照 删除外键、删除索引、删除主键、建表、加列、改列、加主键、加索引、加外键、删列、删表 的顺序执行，down为每一步的逆操作并逆序执行
+func diffSchema(from, to *Schema) []migrationStep {
+	d := migrationDialectOf(from.Dialect)
+	var dropFKs, dropIndexes, dropPKs, createTables, addColumns, alterColumns, addPKs, addIndexes, addFKs, dropColumns, dropTables []migrationStep
+
+	for _, ft := range from.Tables {
+		tt := to.Table(ft.Name)
+		if tt == nil {
+			for _, fk := range ft.ForeignKeys {
+				dropFKs = append(dropFKs, migrationStep{up: []string{d.dropFK(ft, fk)}, down: []string{d.addFK(ft, fk)}})
+			}
+			dropTables = append(dropTables, migrationStep{
+				up:          []string{d.dropTable(ft)},
+				down:        d.createTable(ft),
+				destructive: true,
+			})
+		}
+	}
+
+	for _, tt := range to.Tables {
+		ft := from.Table(tt.Name)
+		if ft == nil {
+			createTables = append(createTables, migrationStep{up: d.createTable(tt), down: []string{d.dropTable(tt)}})
+			for _, fk := range tt.ForeignKeys {
+				addFKs = append(addFKs, migrationStep{up: []string{d.addFK(tt, fk)}, down: []string{d.dropFK(tt, fk)}})
+			}
+			continue
+		}
+
+		// foreign keys
+		for _, fk := range ft.ForeignKeys {
+			if !lo.ContainsBy(tt.ForeignKeys, func(fk1 *SchemaForeignKey) bool { return sameForeignKey(fk, fk1) }) {
+				dropFKs = append(dropFKs, migrationStep{up: []string{d.dropFK(ft, fk)}, down: []string{d.addFK(ft, fk)}})
+			}
+		}
+		for _, fk := range tt.ForeignKeys {
+			if !lo.ContainsBy(ft.ForeignKeys, func(fk1 *SchemaForeignKey) bool { return sameForeignKey(fk, fk1) }) {
+				addFKs = append(addFKs, migrationStep{up: []string{d.addFK(tt, fk)}, down: []string{d.dropFK(tt, fk)}})
+			}
+		}
+
+		// indexes
+		for _, idx := range ft.Indexes {
+			if lo.ContainsBy(tt.Indexes, func(idx1 *SchemaIndex) bool { return sameIndex(idx, idx1) }) {
+				continue
+			}
+			if d.name == DialectMysql && lo.ContainsBy(ft.ForeignKeys, func(fk *SchemaForeignKey) bool {
+				return fk.Name == idx.Name || slicesEqualFold(fk.Columns, idx.Columns)
+			}) {
+				// mysql自动为外键建立的索引
+				continue
+			}
+			dropIndexes = append(dropIndexes, migrationStep{up: []string{d.dropIndex(ft, idx)}, down: []string{d.addIndex(ft, idx)}})
+		}
+		for _, idx := range tt.Indexes {
+			if !lo.ContainsBy(ft.Indexes, func(idx1 *SchemaIndex) bool { return sameIndex(idx, idx1) }) {
+				addIndexes = append(addIndexes, migrationStep{up: []string{d.addIndex(tt, idx)}, down: []string{d.dropIndex(tt, idx)}})
+			}
+		}
+
+		// primary key
+		if !slicesEqualFold(ft.PrimaryKey, tt.PrimaryKey) {
+			if len(ft.PrimaryKey) > 0 {
+				dropPKs = append(dropPKs, migrationStep{up: []string{d.dropPK(ft)}, down: []string{d.addPK(ft)}})
+			}
+			if len(tt.PrimaryKey) > 0 {
+				addPKs = append(addPKs, migrationStep{up: []string{d.addPK(tt)}, down: []string{d.dropPK(tt)}})
+			}
+		}
+
+		// columns
+		for _, tc := range tt.Columns {
+			fc := ft.Column(tc.Name)
+			if fc == nil {
+				addColumns = append(addColumns, migrationStep{up: []string{d.addColumn(tt, tc)}, down: []string{d.dropColumn(tt, tc)}})
+			} else if !sameColumn(d.name, fc, tc) {
+				alterColumns = append(alterColumns, migrationStep{
+					up:          d.alterColumn(tt, fc, tc),
+					down:        d.alterColumn(ft, tc, fc),
+					destructive: isNarrowingColumnType(d.name, fc.Type, tc.Type),
+				})
+			}
+		}
+		for _, fc := range ft.Columns {
+			if tt.Column(fc.Name) == nil {
+				dropColumns = append(dropColumns, migrationStep{
+					up:          []string{d.dropColumn(ft, fc)},
+					down:        []string{d.addColumn(ft, fc)},
+					destructive: true,
+				})
+			}
+		}
+	}
+	return lo.Flatten([][]migrationStep{dropFKs, dropIndexes, dropPKs, createTables, addColumns, alterColumns, addPKs, addIndexes, addFKs, dropColumns, dropTables})
+}
+
+// dialect
+
+type migrationDialect struct {
+	name string
+	q    func(string) string
+}
+
+func migrationDialectOf(dialect string) migrationDialect {
+	if dialect == DialectMysql {
+		return migrationDialect{name: dialect, q: func(name string) string { return "`" + name + "`" }}
+	}
+	return migrationDialect{name: dialect, q: postgresQuote}
+}
+
+func (d migrationDialect) columnDef(c *SchemaColumn) string {
+	var b strings.Builder
+	b.WriteString(d.q(c.Name) + " " + c.Type)
+	if c.AutoIncrement {
+		b.WriteString(lo.Ternary(d.name == DialectMysql, " AUTO_INCREMENT", " GENERATED BY DEFAULT AS IDENTITY"))
+	}
+	if c.NotNull {
+		b.WriteString(" NOT NULL")
+	}
+	if c.Default != "" {
+		b.WriteString(" DEFAULT " + c.Default)
+	}
+	if d.name == DialectMysql && c.Comment != "" {
+		// mysql的MODIFY COLUMN会覆盖原有的注释
+		b.WriteString(" COMMENT " + ddlSingleQuote(c.Comment))
+	}
+	return b.String()
+}
+
+func (d migrationDialect) createTable(t *SchemaTable) []string {
+	var defs []string
+	for _, c := range t.Columns {
+		defs = append(defs, "  "+d.columnDef(c))
+	}
+	if len(t.PrimaryKey) > 0 {
+		defs = append(defs, fmt.Sprintf("  PRIMARY KEY (%s)", ddlQuoteList(t.PrimaryKey, d.q)))
+	}
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n%s\n)", d.q(t.Name), strings.Join(defs, ",\n"))
+	if d.name == DialectMysql {
+		stmt += " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	}
+	stmts := []string{stmt + ";"}
+	for _, idx := range t.Indexes {
+		stmts = append(stmts, d.addIndex(t, idx))
+	}
+	return stmts
+}
+
+func (d migrationDialect) dropTable(t *SchemaTable) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", d.q(t.Name))
+}
+
+func (d migrationDialect) addColumn(t *SchemaTable, c *SchemaColumn) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", d.q(t.Name), d.columnDef(c))
+}
+
+func (d migrationDialect) dropColumn(t *SchemaTable, c *SchemaColumn) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", d.q(t.Name), d.q(c.Name))
+}
+
+func (d migrationDialect) alterColumn(t *SchemaTable, from, to *SchemaColumn) []string {
+	if d.name == DialectMysql {
+		if to.Comment == "" {
+			to1 := *to
+			to1.Comment = from.Comment
+			to = &to1
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", d.q(t.Name), d.columnDef(to))}
+	}
+	prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", d.q(t.Name), d.q(to.Name))
+	var stmts []string
+	if from.AutoIncrement && !to.AutoIncrement {
+		stmts = append(stmts, prefix+" DROP IDENTITY IF EXISTS;")
+	}
+	if normalizeColumnType(d.name, from.Type) != normalizeColumnType(d.name, to.Type) {
+		stmts = append(stmts, fmt.Sprintf("%s TYPE %s USING %s::%s;", prefix, to.Type, d.q(to.Name), to.Type))
+	}
+	if from.NotNull != to.NotNull {
+		stmts = append(stmts, prefix+lo.Ternary(to.NotNull, " SET NOT NULL;", " DROP NOT NULL;"))
+	}
+	if normalizeDefault(d.name, from.Default) != normalizeDefault(d.name, to.Default) {
+		stmts = append(stmts, prefix+lo.Ternary(to.Default != "", " SET DEFAULT "+to.Default+";", " DROP DEFAULT;"))
+	}
+	if !from.AutoIncrement && to.AutoIncrement {
+		stmts = append(stmts, prefix+" ADD GENERATED BY DEFAULT AS IDENTITY;")
+	}
+	return stmts
+}
+
+func (d migrationDialect) addPK(t *SchemaTable) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", d.q(t.Name), ddlQuoteList(t.PrimaryKey, d.q))
+}
+
+func (d migrationDialect) dropPK(t *SchemaTable) string {
+	if d.name == DialectMysql {
+		return fmt.Sprintf("ALTER TABLE %s DROP PRIMARY KEY;", d.q(t.Name))
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", d.q(t.Name), d.q(t.Name+"_pkey"))
+}
+
+func (d migrationDialect) addIndex(t *SchemaTable, idx *SchemaIndex) string {
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", lo.Ternary(idx.Unique, "UNIQUE ", ""), d.q(idx.Name), d.q(t.Name), ddlQuoteList(idx.Columns, d.q))
+}
+
+func (d migrationDialect) dropIndex(t *SchemaTable, idx *SchemaIndex) string {
+	if d.name == DialectMysql {
+		return fmt.Sprintf("DROP INDEX %s ON %s;", d.q(idx.Name), d.q(t.Name))
+	}
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s;", d.q(idx.Name))
+}
+
+func (d migrationDialect) addFK(t *SchemaTable, fk *SchemaForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s);",
+		d.q(t.Name), d.q(fk.Name), ddlQuoteList(fk.Columns, d.q), d.q(fk.RefTable), ddlQuoteList(fk.RefColumns, d.q))
+}
+
+func (d migrationDialect) dropFK(t *SchemaTable, fk *SchemaForeignKey) string {
+	if d.name == DialectMysql {
+		return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", d.q(t.Name), d.q(fk.Name))
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", d.q(t.Name), d.q(fk.Name))
+}
@@ -0,0 +1,261 @@
+package sdblueprint
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeColumnType(t *testing.T) {
+	for _, c := range []struct {
+		dialect, typ, expected string
+	}{
+		{DialectMysql, "INT(11)", "int"},
+		{DialectMysql, "bigint(20) unsigned", "bigint unsigned"},
+		{DialectMysql, "INTEGER", "int"},
+		{DialectMysql, "BOOLEAN", "tinyint"},
+		{DialectMysql, "DOUBLE PRECISION", "double"},
+		{DialectMysql, "DECIMAL(10, 2)", "decimal(10,2)"},
+		{DialectMysql, "VARCHAR(255)", "varchar(255)"},
+		{DialectPostgres, "character varying(32)", "varchar(32)"},
+		{DialectPostgres, "character(2)", "char(2)"},
+		{DialectPostgres, "int8", "bigint"},
+		{DialectPostgres, "BIGSERIAL", "bigint"},
+		{DialectPostgres, "int", "integer"},
+		{DialectPostgres, "float8", "double precision"},
+		{DialectPostgres, "bool", "boolean"},
+		{DialectPostgres, "numeric(10,0)", "numeric(10)"},
+		{DialectPostgres, "text", "text"},
+	} {
+		assert.Equal(t, c.expected, normalizeColumnType(c.dialect, c.typ), c.typ)
+	}
+}
+
+func TestNormalizeDefault(t *testing.T) {
+	for _, c := range []struct {
+		dialect, def, expected string
+	}{
+		{DialectMysql, "", ""},
+		{DialectMysql, "NULL", ""},
+		{DialectMysql, "0", "0"},
+		{DialectMysql, "1.50", "1.5"},
+		{DialectMysql, "'abc'", "'abc'"},
+		{DialectMysql, "TRUE", "true"},
+		{DialectPostgres, "'abc'::character varying", "'abc'"},
+		{DialectPostgres, "'abc'::character varying(32)", "'abc'"},
+		{DialectPostgres, "(0)::bigint", "0"},
+		{DialectPostgres, "NULL::text", ""},
+		{DialectPostgres, "false", "false"},
+		{DialectPostgres, "now()", "now()"},
+	} {
+		assert.Equal(t, c.expected, normalizeDefault(c.dialect, c.def), c.def)
+	}
+}
+
+func TestIsNarrowingColumnType(t *testing.T) {
+	for _, c := range []struct {
+		dialect, from, to string
+		expected          bool
+	}{
+		{DialectMysql, "varchar(255)", "VARCHAR(255)", false},
+		{DialectMysql, "varchar(32)", "varchar(255)", false},
+		{DialectMysql, "varchar(255)", "varchar(32)", true},
+		{DialectMysql, "int(11)", "bigint", false},
+		{DialectMysql, "bigint(20)", "int", true},
+		{DialectMysql, "int unsigned", "int", true},
+		{DialectMysql, "varchar(255)", "text", false},
+		{DialectMysql, "longtext", "text", true},
+		{DialectMysql, "decimal(10,2)", "decimal(12,2)", false},
+		{DialectMysql, "decimal(10,2)", "decimal(10,1)", true},
+		{DialectMysql, "double", "float", true},
+		{DialectMysql, "varchar(32)", "int", true},
+		{DialectPostgres, "integer", "bigint", false},
+		{DialectPostgres, "int8", "integer", true},
+		{DialectPostgres, "character varying(255)", "varchar(32)", true},
+		{DialectPostgres, "varchar(255)", "text", false},
+		{DialectPostgres, "text", "varchar(32)", true},
+		{DialectPostgres, "varchar", "varchar(32)", true},
+	} {
+		assert.Equal(t, c.expected, isNarrowingColumnType(c.dialect, c.from, c.to), c.from+" -> "+c.to)
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	users := func(columns ...*SchemaColumn) *SchemaTable {
+		return &SchemaTable{
+			Name: "users",
+			Columns: append([]*SchemaColumn{
+				{Name: "id", Type: "bigint", NotNull: true, AutoIncrement: true},
+			}, columns...),
+			PrimaryKey: []string{"id"},
+		}
+	}
+	schema := func(dialect string, tables ...*SchemaTable) *Schema {
+		return &Schema{Dialect: dialect, Tables: tables}
+	}
+	name := func(typ string) *SchemaColumn {
+		return &SchemaColumn{Name: "name", Type: typ, NotNull: true, Default: "''", Comment: "名称"}
+	}
+
+	for _, c := range []struct {
+		name        string
+		from, to    *Schema
+		up, down    [][]string
+		destructive []bool
+	}{
+		{
+			name: "mysql same",
+			from: schema(DialectMysql, users(&SchemaColumn{Name: "name", Type: "VARCHAR(32)", NotNull: true, Default: "''"})),
+			to:   schema(DialectMysql, users(name("varchar(32)"))),
+		},
+		{
+			name: "mysql create table",
+			from: schema(DialectMysql),
+			to:   schema(DialectMysql, users()),
+			up: [][]string{{
+				"CREATE TABLE `users` (\n  `id` bigint AUTO_INCREMENT NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;",
+			}},
+			down:        [][]string{{"DROP TABLE IF EXISTS `users`;"}},
+			destructive: []bool{false},
+		},
+		{
+			name:        "mysql drop table",
+			from:        schema(DialectMysql, users()),
+			to:          schema(DialectMysql),
+			up:          [][]string{{"DROP TABLE IF EXISTS `users`;"}},
+			destructive: []bool{true},
+		},
+		{
+			name:        "mysql add column",
+			from:        schema(DialectMysql, users()),
+			to:          schema(DialectMysql, users(name("varchar(32)"))),
+			up:          [][]string{{"ALTER TABLE `users` ADD COLUMN `name` varchar(32) NOT NULL DEFAULT '' COMMENT '名称';"}},
+			down:        [][]string{{"ALTER TABLE `users` DROP COLUMN `name`;"}},
+			destructive: []bool{false},
+		},
+		{
+			name:        "mysql drop column",
+			from:        schema(DialectMysql, users(name("varchar(32)"))),
+			to:          schema(DialectMysql, users()),
+			up:          [][]string{{"ALTER TABLE `users` DROP COLUMN `name`;"}},
+			down:        [][]string{{"ALTER TABLE `users` ADD COLUMN `name` varchar(32) NOT NULL DEFAULT '' COMMENT '名称';"}},
+			destructive: []bool{true},
+		},
+		{
+			name:        "mysql widen column",
+			from:        schema(DialectMysql, users(name("varchar(32)"))),
+			to:          schema(DialectMysql, users(name("varchar(255)"))),
+			up:          [][]string{{"ALTER TABLE `users` MODIFY COLUMN `name` varchar(255) NOT NULL DEFAULT '' COMMENT '名称';"}},
+			down:        [][]string{{"ALTER TABLE `users` MODIFY COLUMN `name` varchar(32) NOT NULL DEFAULT '' COMMENT '名称';"}},
+			destructive: []bool{false},
+		},
+		{
+			name:        "mysql narrow column",
+			from:        schema(DialectMysql, users(name("varchar(255)"))),
+			to:          schema(DialectMysql, users(name("varchar(32)"))),
+			up:          [][]string{{"ALTER TABLE `users` MODIFY COLUMN `name` varchar(32) NOT NULL DEFAULT '' COMMENT '名称';"}},
+			down:        [][]string{{"ALTER TABLE `users` MODIFY COLUMN `name` varchar(255) NOT NULL DEFAULT '' COMMENT '名称';"}},
+			destructive: []bool{true},
+		},
+		{
+			name: "mysql keep comment",
+			from: schema(DialectMysql, users(name("varchar(32)"))),
+			to: schema(DialectMysql, users(
+				&SchemaColumn{Name: "name", Type: "varchar(32)", NotNull: false, Default: "''"},
+			)),
+			up:          [][]string{{"ALTER TABLE `users` MODIFY COLUMN `name` varchar(32) DEFAULT '' COMMENT '名称';"}},
+			down:        [][]string{{"ALTER TABLE `users` MODIFY COLUMN `name` varchar(32) NOT NULL DEFAULT '' COMMENT '名称';"}},
+			destructive: []bool{false},
+		},
+		{
+			name: "postgres same",
+			from: schema(DialectPostgres, users(&SchemaColumn{Name: "name", Type: "character varying(32)", NotNull: true, Default: "''::character varying"})),
+			to:   schema(DialectPostgres, users(name("varchar(32)"))),
+		},
+		{
+			name: "postgres create table",
+			from: schema(DialectPostgres),
+			to:   schema(DialectPostgres, users()),
+			up: [][]string{{
+				"CREATE TABLE \"users\" (\n  \"id\" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL,\n  PRIMARY KEY (\"id\")\n);",
+			}},
+			down:        [][]string{{`DROP TABLE IF EXISTS "users";`}},
+			destructive: []bool{false},
+		},
+		{
+			name:        "postgres add column",
+			from:        schema(DialectPostgres, users()),
+			to:          schema(DialectPostgres, users(name("varchar(32)"))),
+			up:          [][]string{{`ALTER TABLE "users" ADD COLUMN "name" varchar(32) NOT NULL DEFAULT '';`}},
+			down:        [][]string{{`ALTER TABLE "users" DROP COLUMN "name";`}},
+			destructive: []bool{false},
+		},
+		{
+			name:        "postgres drop column",
+			from:        schema(DialectPostgres, users(name("varchar(32)"))),
+			to:          schema(DialectPostgres, users()),
+			up:          [][]string{{`ALTER TABLE "users" DROP COLUMN "name";`}},
+			down:        [][]string{{`ALTER TABLE "users" ADD COLUMN "name" varchar(32) NOT NULL DEFAULT '';`}},
+			destructive: []bool{true},
+		},
+		{
+			name: "postgres narrow column",
+			from: schema(DialectPostgres, users(name("varchar(255)"))),
+			to: schema(DialectPostgres, users(
+				&SchemaColumn{Name: "name", Type: "varchar(32)"},
+			)),
+			up: [][]string{{
+				`ALTER TABLE "users" ALTER COLUMN "name" TYPE varchar(32) USING "name"::varchar(32);`,
+				`ALTER TABLE "users" ALTER COLUMN "name" DROP NOT NULL;`,
+				`ALTER TABLE "users" ALTER COLUMN "name" DROP DEFAULT;`,
+			}},
+			down: [][]string{{
+				`ALTER TABLE "users" ALTER COLUMN "name" TYPE varchar(255) USING "name"::varchar(255);`,
+				`ALTER TABLE "users" ALTER COLUMN "name" SET NOT NULL;`,
+				`ALTER TABLE "users" ALTER COLUMN "name" SET DEFAULT '';`,
+			}},
+			destructive: []bool{true},
+		},
+	} {
+		steps := diffSchema(c.from, c.to)
+		assert.Len(t, steps, len(c.up), c.name)
+		for i, step := range steps {
+			if i >= len(c.up) {
+				break
+			}
+			assert.Equal(t, c.up[i], step.up, c.name)
+			if c.down != nil {
+				assert.Equal(t, c.down[i], step.down, c.name)
+			}
+			assert.Equal(t, c.destructive[i], step.destructive, c.name)
+		}
+	}
+}
+
+func TestMergeSnapshot(t *testing.T) {
+	load := func(tables ...string) *Blueprint {
+		doc := `{"tables":[`
+		for i, id := range tables {
+			if i > 0 {
+				doc += ","
+			}
+			doc += `{"id":"` + id[:1] + `","comment":"` + id + `","columns":[{"id":"id","type":"int64"}]}`
+		}
+		bp, err := LoadSnapshot([]byte(doc + `]}`))
+		assert.NoError(t, err)
+		return bp
+	}
+	comments := func(bp *Blueprint) []string {
+		var r []string
+		for _, t := range bp.Tables() {
+			r = append(r, t.Comment())
+		}
+		return r
+	}
+
+	prev := load("a0", "b0", "c0")
+	bp := load("a1", "b1", "c1", "d1")
+	assert.Equal(t, []string{"a0", "b1", "c0"}, comments(mergeSnapshot(prev, bp, []string{"b"})))
+	assert.Equal(t, []string{"a0", "b0", "c0", "d1"}, comments(mergeSnapshot(prev, bp, []string{"d"})))
+	assert.Equal(t, []string{"a1"}, comments(mergeSnapshot(nil, bp, []string{"a"})))
+	assert.Equal(t, []string{"a1", "c0"}, comments(mergeSnapshot(prev, load("a1", "c1"), []string{"a", "b"})))
+}
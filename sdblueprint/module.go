@@ -35,12 +35,40 @@ type ModuleTaskGenerateMysqlDDL struct {
 	Groups  []string `json:"groups,omitempty"`
 }
 
+type ModuleTaskGeneratePostgresDDL struct {
+	Dirname string   `json:"dir,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+	Schema  string   `json:"schema,omitempty"`
+}
+
+type ModuleTaskGenerateSqliteDDL struct {
+	Dirname string   `json:"dir,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+}
+
+type ModuleTaskGenerateCrud struct {
+	Dirname string   `json:"dir,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+	Driver  string   `json:"driver,omitempty"`
+	WithAPI bool     `json:"api,omitempty"`
+}
+
+type ModuleTaskGenerateTypeScriptModel struct {
+	Dirname     string   `json:"dir,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+	WithColumns bool     `json:"columns,omitempty"`
+}
+
 var (
 	_ Module     = &module{}
 	_ moduleTask = &ModuleTaskGenerateSkeleton{}
 	_ moduleTask = &ModuleTaskGenerateGormModel{}
 	_ moduleTask = &ModuleTaskGenerateBunModel{}
 	_ moduleTask = &ModuleTaskGenerateMysqlDDL{}
+	_ moduleTask = &ModuleTaskGeneratePostgresDDL{}
+	_ moduleTask = &ModuleTaskGenerateSqliteDDL{}
+	_ moduleTask = &ModuleTaskGenerateCrud{}
+	_ moduleTask = &ModuleTaskGenerateTypeScriptModel{}
 )
 
 type module struct {
@@ -125,6 +153,42 @@ func (t *ModuleTaskGenerateMysqlDDL) clone() any {
 	return &t1
 }
 
+func (t *ModuleTaskGeneratePostgresDDL) clone() any {
+	if t == nil {
+		return nil
+	}
+	t1 := *t
+	t1.Groups = slices.Clone(t.Groups)
+	return &t1
+}
+
+func (t *ModuleTaskGenerateSqliteDDL) clone() any {
+	if t == nil {
+		return nil
+	}
+	t1 := *t
+	t1.Groups = slices.Clone(t.Groups)
+	return &t1
+}
+
+func (t *ModuleTaskGenerateCrud) clone() any {
+	if t == nil {
+		return nil
+	}
+	t1 := *t
+	t1.Groups = slices.Clone(t.Groups)
+	return &t1
+}
+
+func (t *ModuleTaskGenerateTypeScriptModel) clone() any {
+	if t == nil {
+		return nil
+	}
+	t1 := *t
+	t1.Groups = slices.Clone(t.Groups)
+	return &t1
+}
+
 func (t *ModuleTaskGenerateSkeleton) ToJsonObject() sdjson.Object {
 	if t == nil {
 		return nil
@@ -153,6 +217,34 @@ func (t *ModuleTaskGenerateMysqlDDL) ToJsonObject() sdjson.Object {
 	return moduleTaskToJson(t, "generate_mysql_ddl")
 }
 
+func (t *ModuleTaskGeneratePostgresDDL) ToJsonObject() sdjson.Object {
+	if t == nil {
+		return nil
+	}
+	return moduleTaskToJson(t, "generate_postgres_ddl")
+}
+
+func (t *ModuleTaskGenerateSqliteDDL) ToJsonObject() sdjson.Object {
+	if t == nil {
+		return nil
+	}
+	return moduleTaskToJson(t, "generate_sqlite_ddl")
+}
+
+func (t *ModuleTaskGenerateCrud) ToJsonObject() sdjson.Object {
+	if t == nil {
+		return nil
+	}
+	return moduleTaskToJson(t, "generate_crud")
+}
+
+func (t *ModuleTaskGenerateTypeScriptModel) ToJsonObject() sdjson.Object {
+	if t == nil {
+		return nil
+	}
+	return moduleTaskToJson(t, "generate_typescript_model")
+}
+
 func moduleTaskToJson(v any, typ string) sdjson.Object {
 	o, err := sdjson.StructToObject(v)
 	if err != nil {
@@ -0,0 +1,55 @@
+package sdblueprint
+
+import (
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdsqlparser"
+)
+
+// checkQuerySQL 生成代码前检查query中的SQL，SQL中的@name必须是函数的参数，
+// 语法检查使用sdsqlparser(mysql语法)，只在checkSyntax时进行，非mysql方言不要开启，
+// 单个query可以使用sql_check:"true"/"false"覆盖
+func checkQuerySQL(q Query, checkSyntax bool) error {
+	switch q.Kind() {
+	case QueryForCreate, QueryForUpdate:
+		return nil
+	}
+	for _, name := range sdsqlparser.NamedArgs(q.SQL()) {
+		if q.ParamByName(name) == nil {
+			return sderr.NewWith("undeclared param in query SQL", sderr.Attrs{"q": q.Id(), "param": name})
+		}
+	}
+	if !q.Get("sql_check").AsBool(checkSyntax) {
+		return nil
+	}
+	if err := sdsqlparser.Check(q.SQL()); err != nil {
+		return sderr.WrapWith(err, "illegal SQL in query", q.Id())
+	}
+	return nil
+}
+
+// gormQuerySQL GORM会将slice参数展开为(a,b,c)，所以去掉列表参数外的括号
+func gormQuerySQL(q Query) string {
+	return sdsqlparser.ReplaceNamedArgs(q.SQL(), func(name string, parenthesized bool) string {
+		if q.ParamByName(name).IsList() || !parenthesized {
+			return "@" + name
+		}
+		return "(@" + name + ")"
+	})
+}
+
+// bunQuerySQL BUN使用?作为参数，返回替换后的SQL和参数表达式，列表参数使用bun.In展开
+func bunQuerySQL(q Query) (string, []string) {
+	var args []string
+	sql := sdsqlparser.ReplaceNamedArgs(q.SQL(), func(name string, parenthesized bool) string {
+		if q.ParamByName(name).IsList() {
+			args = append(args, "bun.In("+name+")")
+			return "(?)"
+		}
+		args = append(args, name)
+		if parenthesized {
+			return "(?)"
+		}
+		return "?"
+	})
+	return sql, args
+}
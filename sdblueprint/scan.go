@@ -85,8 +85,8 @@ func scanTableProto(bp *Blueprint, sv reflect.Value, st structType, mark markedF
 				attributes: func() attributes {
 					attrs := attributes{}
 					structTag(sf.Tag).toAttrs(attrs,
-						"json", "xml", "validate", "go", "go_type", "go_import", "default", "db_type", "dbtype",
-						"pk", "primary_key", "unique", "index",
+						"json", "xml", "validate", "go", "go_type", "go_import", "default", "db_type", "dbtype", "postgres_type", "sqlite_type",
+						"pk", "primary_key", "unique", "index", "enum", "ts_type",
 					)
 					structTag(sf.Tag).toAttrsForFlags(attrs, "db")
 					return attrs
@@ -165,7 +165,7 @@ func addQueryWithField(bp *Blueprint, st *structType, mark markedField, inTable
 	q1 := bp.addQuery(id, q, qft).setComment(mark.tag.comment()).setGroup(mark.tag.group()).setTable(tableId)
 	q1.attributes = func() attributes {
 		attrs := attributes{}
-		mark.tag.toAttrs(attrs, "return_row_affected")
+		mark.tag.toAttrs(attrs, "return_row_affected", "sql_check")
 		return attrs
 	}()
 	return nil
@@ -181,7 +181,7 @@ func scanModuleProto(bp *Blueprint, _ reflect.Value, st structType, mark markedF
 		mark1, ok := getFieldMark(sf.Type, allMarks)
 		attrs := func() attributes {
 			attrs0 := attributes{}
-			st.toAttrs(attrs0, "template", "dir", "group", "groups", "query_with_context")
+			st.toAttrs(attrs0, "template", "dir", "group", "groups", "query_with_context", "schema", "driver", "api", "columns")
 			return attrs0
 		}()
 		if ok {
@@ -208,6 +208,30 @@ func scanModuleProto(bp *Blueprint, _ reflect.Value, st structType, mark markedF
 					Dirname: attrs.Get("dir").AsStr(),
 					Groups:  attrs.First([]string{"groups", "group"}).AsSlice(","),
 				})
+			} else if mark1 == markAsGeneratePostgresDDL {
+				newModule.addTask(&ModuleTaskGeneratePostgresDDL{
+					Dirname: attrs.Get("dir").AsStr(),
+					Groups:  attrs.First([]string{"groups", "group"}).AsSlice(","),
+					Schema:  attrs.Get("schema").AsStr(),
+				})
+			} else if mark1 == markAsGenerateSqliteDDL {
+				newModule.addTask(&ModuleTaskGenerateSqliteDDL{
+					Dirname: attrs.Get("dir").AsStr(),
+					Groups:  attrs.First([]string{"groups", "group"}).AsSlice(","),
+				})
+			} else if mark1 == markAsGenerateCrud {
+				newModule.addTask(&ModuleTaskGenerateCrud{
+					Dirname: attrs.Get("dir").AsStr(),
+					Groups:  attrs.First([]string{"groups", "group"}).AsSlice(","),
+					Driver:  attrs.Get("driver").AsStr(),
+					WithAPI: attrs.Get("api").AsBool(false),
+				})
+			} else if mark1 == markAsGenerateTypeScriptModel {
+				newModule.addTask(&ModuleTaskGenerateTypeScriptModel{
+					Dirname:     attrs.Get("dir").AsStr(),
+					Groups:      attrs.First([]string{"groups", "group"}).AsSlice(","),
+					WithColumns: attrs.Get("columns").AsBool(false),
+				})
 			} else {
 				return sderr.NewWith("illegal mark in module", mark1)
 			}
@@ -0,0 +1,323 @@
+package sdblueprint
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdparse"
+	"github.com/samber/lo"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	DialectMysql    = "mysql"
+	DialectPostgres = "postgres"
+)
+
+// Schema 数据库中的表结构，由Blueprint(SchemaOf)或者数据库(SchemaFromDB)得到，用于生成Migration
+type Schema struct {
+	Dialect string
+	Tables  []*SchemaTable
+}
+
+type SchemaTable struct {
+	Name        string
+	Columns     []*SchemaColumn
+	PrimaryKey  []string
+	Indexes     []*SchemaIndex
+	ForeignKeys []*SchemaForeignKey
+}
+
+type SchemaColumn struct {
+	Name          string
+	Type          string
+	NotNull       bool
+	Default       string // SQL字面量，为空表示没有默认值
+	AutoIncrement bool
+	Comment       string
+}
+
+type SchemaIndex struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+type SchemaForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// SchemaOf 按照dialect的DDL生成规则得到bp中表的结构
+func SchemaOf(bp *Blueprint, dialect string) (*Schema, error) {
+	if dialect != DialectMysql && dialect != DialectPostgres {
+		return nil, sderr.NewWith("illegal schema dialect", dialect)
+	}
+	schema := &Schema{Dialect: dialect}
+	for _, t := range bp.Tables() {
+		st, err := schemaTableOf(bp, t, dialect)
+		if err != nil {
+			return nil, sderr.WithStack(err)
+		}
+		schema.Tables = append(schema.Tables, st)
+	}
+	return schema, nil
+}
+
+func schemaTableOf(bp *Blueprint, t Table, dialect string) (st *SchemaTable, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = sderr.NewWith("get table schema error", sderr.Attrs{"t": t.Id(), "err": fmt.Sprint(r)})
+		}
+	}()
+	st = &SchemaTable{Name: t.NameForDB()}
+	for _, c := range t.Columns() {
+		sc := &SchemaColumn{
+			Name:          c.NameForDB(),
+			NotNull:       !c.IsAllowNull(),
+			AutoIncrement: c.IsAutoIncrement(),
+			Comment:       c.Comment(),
+		}
+		if dialect == DialectMysql {
+			sc.Type = mysqlDataTypeOf(c)
+		} else {
+			sc.Type = postgresDataTypeOf(c)
+		}
+		if def := c.Default(); def != nil && !(dialect == DialectPostgres && sc.AutoIncrement) {
+			// 与PostgresDDL一致，identity列没有默认值
+			sc.Default = schemaDefaultLiteral(dialect, sc.Type, def)
+		}
+		st.Columns = append(st.Columns, sc)
+	}
+	numFK := 0
+	for _, idx := range t.Indexes() {
+		switch idx.Kind() {
+		case IndexPK:
+			st.PrimaryKey = ddlColumnNames(t, idx.Columns())
+		case IndexSimple, IndexUnique:
+			si := &SchemaIndex{Unique: idx.Kind() == IndexUnique, Columns: ddlColumnNames(t, idx.Columns())}
+			if dialect == DialectMysql {
+				// 与MysqlDDL一致，没有名称时mysql使用第一列作为索引名称
+				si.Name = lo.Ternary(idx.Name() != "", idx.Name(), si.Columns[0])
+			} else {
+				si.Name = ddlIndexName(t, idx, lo.Ternary(si.Unique, "uk", "idx"))
+			}
+			st.Indexes = append(st.Indexes, si)
+		case IndexFK:
+			numFK++
+			refTable := bp.Table(idx.ReferenceTable())
+			if refTable == nil {
+				return nil, sderr.NewWith("not found foreign key reference table", idx.ReferenceTable())
+			}
+			fk := &SchemaForeignKey{
+				Name:       idx.Name(),
+				Columns:    ddlColumnNames(t, idx.Columns()),
+				RefTable:   refTable.NameForDB(),
+				RefColumns: ddlColumnNames(refTable, idx.ReferenceColumns()),
+			}
+			if fk.Name == "" {
+				// 与数据库自动生成的名称一致
+				if dialect == DialectMysql {
+					fk.Name = fmt.Sprintf("%s_ibfk_%d", st.Name, numFK)
+				} else {
+					fk.Name = st.Name + "_" + strings.Join(fk.Columns, "_") + "_fkey"
+				}
+			}
+			st.ForeignKeys = append(st.ForeignKeys, fk)
+		}
+	}
+	return st, nil
+}
+
+func (s *Schema) Table(name string) *SchemaTable {
+	if s == nil {
+		return nil
+	}
+	return lo.FindOrElse(s.Tables, nil, func(t *SchemaTable) bool { return t.Name == name })
+}
+
+func (t *SchemaTable) Column(name string) *SchemaColumn {
+	return lo.FindOrElse(t.Columns, nil, func(c *SchemaColumn) bool { return c.Name == name })
+}
+
+func schemaDefaultLiteral(dialect, sqlType string, v any) string {
+	if dialect == DialectPostgres {
+		return postgresDefaultLiteral(sqlType, v)
+	}
+	switch v1 := v.(type) {
+	case string:
+		return ddlSingleQuote(v1)
+	case bool:
+		return lo.Ternary(v1, "1", "0")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// normalize
+
+var (
+	pattMysqlIntWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|integer|bigint)\(\d+\)`)
+	pattPostgresCast  = regexp.MustCompile(`::[\w\s"]+(\(\d+(,\d+)?\))?(\[\])?$`)
+	pattSpaces        = regexp.MustCompile(`\s+`)
+)
+
+func normalizeColumnType(dialect, typ string) string {
+	typ = pattSpaces.ReplaceAllString(strings.ToLower(strings.TrimSpace(typ)), " ")
+	typ = strings.ReplaceAll(typ, ", ", ",")
+	if dialect == DialectMysql {
+		typ = pattMysqlIntWidth.ReplaceAllString(typ, "$1")
+		switch {
+		case strings.HasPrefix(typ, "integer"):
+			typ = "int" + strings.TrimPrefix(typ, "integer")
+		case typ == "bool" || typ == "boolean":
+			typ = "tinyint"
+		case typ == "double precision":
+			typ = "double"
+		}
+		return typ
+	}
+	for _, alias := range [][2]string{
+		{"character varying", "varchar"},
+		{"character", "char"},
+		{"int8", "bigint"},
+		{"bigserial", "bigint"},
+		{"int4", "integer"},
+		{"serial", "integer"},
+		{"int2", "smallint"},
+		{"float8", "double precision"},
+		{"float4", "real"},
+		{"bool", "boolean"},
+		{"int", "integer"},
+	} {
+		if typ == alias[0] || strings.HasPrefix(typ, alias[0]+"(") {
+			typ = alias[1] + strings.TrimPrefix(typ, alias[0])
+			break
+		}
+	}
+	if strings.HasPrefix(typ, "numeric(") && strings.HasSuffix(typ, ",0)") {
+		typ = strings.TrimSuffix(typ, ",0)") + ")"
+	}
+	return typ
+}
+
+func normalizeDefault(dialect, def string) string {
+	def = strings.TrimSpace(def)
+	if dialect == DialectPostgres {
+		for pattPostgresCast.MatchString(def) {
+			def = strings.TrimSpace(pattPostgresCast.ReplaceAllString(def, ""))
+		}
+		if strings.HasPrefix(def, "(") && strings.HasSuffix(def, ")") {
+			def = strings.TrimSpace(def[1 : len(def)-1])
+		}
+	}
+	if strings.EqualFold(def, "null") {
+		return ""
+	}
+	if strings.EqualFold(def, "true") || strings.EqualFold(def, "false") {
+		return strings.ToLower(def)
+	}
+	if f, err := strconv.ParseFloat(def, 64); err == nil {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return def
+}
+
+// 按照容量从小到大排列的同类类型
+var columnTypeFamilies = map[string][][]string{
+	DialectMysql: {
+		{"tinyint", "smallint", "mediumint", "int", "bigint"},
+		{"float", "double"},
+		{"char", "varchar", "tinytext", "text", "mediumtext", "longtext"},
+		{"binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob"},
+	},
+	DialectPostgres: {
+		{"smallint", "integer", "bigint"},
+		{"real", "double precision"},
+		{"char", "varchar", "text"},
+	},
+}
+
+// isNarrowingColumnType 修改列类型是否可能丢失或者截断数据，无法确定时认为会
+func isNarrowingColumnType(dialect, from, to string) bool {
+	from, to = normalizeColumnType(dialect, from), normalizeColumnType(dialect, to)
+	if from == to {
+		return false
+	}
+	fromBase, fromArgs, fromUnsigned := splitColumnType(from)
+	toBase, toArgs, toUnsigned := splitColumnType(to)
+	if fromUnsigned != toUnsigned {
+		return true
+	}
+	if fromBase == toBase {
+		if len(fromArgs) <= 0 {
+			return len(toArgs) > 0 && dialect == DialectPostgres
+		}
+		for i, arg := range toArgs {
+			if i < len(fromArgs) && arg < fromArgs[i] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, family := range columnTypeFamilies[dialect] {
+		fromRank, toRank := slices.Index(family, fromBase), slices.Index(family, toBase)
+		if fromRank >= 0 && toRank >= 0 {
+			if toRank < fromRank {
+				return true
+			}
+			// 变长的字符串变为定长
+			return len(toArgs) > 0 && len(fromArgs) > 0 && toArgs[0] < fromArgs[0]
+		}
+	}
+	return true
+}
+
+// splitColumnType 将normalizeColumnType的结果拆分为 类型名、参数、是否unsigned
+func splitColumnType(typ string) (string, []int64, bool) {
+	unsigned := strings.Contains(typ, " unsigned")
+	typ = strings.TrimSpace(strings.NewReplacer(" unsigned", "", " zerofill", "").Replace(typ))
+	base, rest, found := strings.Cut(typ, "(")
+	if !found {
+		return typ, nil, unsigned
+	}
+	argsStr, suffix, _ := strings.Cut(rest, ")")
+	var args []int64
+	for _, arg := range strings.Split(argsStr, ",") {
+		args = append(args, sdparse.Int64Def(strings.TrimSpace(arg), 0))
+	}
+	return strings.TrimSpace(base + suffix), args, unsigned
+}
+
+func sameColumn(dialect string, a, b *SchemaColumn) bool {
+	return normalizeColumnType(dialect, a.Type) == normalizeColumnType(dialect, b.Type) &&
+		a.NotNull == b.NotNull &&
+		a.AutoIncrement == b.AutoIncrement &&
+		normalizeDefault(dialect, a.Default) == normalizeDefault(dialect, b.Default)
+}
+
+func sameIndex(a, b *SchemaIndex) bool {
+	return a.Unique == b.Unique && slicesEqualFold(a.Columns, b.Columns)
+}
+
+func sameForeignKey(a, b *SchemaForeignKey) bool {
+	return slicesEqualFold(a.Columns, b.Columns) &&
+		strings.EqualFold(a.RefTable, b.RefTable) &&
+		slicesEqualFold(a.RefColumns, b.RefColumns)
+}
+
+func slicesEqualFold(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
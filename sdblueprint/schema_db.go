@@ -0,0 +1,237 @@
+package sdblueprint
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// SchemaFromDB 从数据库的information_schema中读取表结构，tableNames为空时读取当前库中所有的表，目前支持mysql和postgres
+func SchemaFromDB(tx *gorm.DB, tableNames ...string) (*Schema, error) {
+	var schema *Schema
+	var err error
+	switch dialect := tx.Dialector.Name(); dialect {
+	case DialectMysql:
+		schema, err = mysqlSchemaFromDB(tx)
+	case DialectPostgres:
+		schema, err = postgresSchemaFromDB(tx)
+	default:
+		return nil, sderr.NewWith("unsupported dialect for schema", dialect)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(tableNames) > 0 {
+		schema.Tables = lo.Filter(schema.Tables, func(t *SchemaTable, _ int) bool {
+			return lo.Contains(tableNames, t.Name)
+		})
+	}
+	return schema, nil
+}
+
+type schemaDBColumn struct {
+	TableName     string
+	ColumnName    string
+	ColumnType    string
+	NotNull       bool
+	ColumnDefault sql.NullString
+	AutoIncrement bool
+	ColumnComment string
+}
+
+type postgresDBColumn struct {
+	schemaDBColumn
+	DataType string
+	CharLen  sql.NullInt64
+	NumPrec  sql.NullInt64
+	NumScale sql.NullInt64
+}
+
+type schemaDBIndex struct {
+	TableName  string
+	IndexName  string
+	Primary    bool
+	Unique     bool
+	ColumnName string
+}
+
+type schemaDBForeignKey struct {
+	TableName     string
+	ConstName     string
+	ColumnName    string
+	RefTableName  string
+	RefColumnName string
+}
+
+func mysqlSchemaFromDB(tx *gorm.DB) (*Schema, error) {
+	var columns []schemaDBColumn
+	err := tx.Raw(`
+SELECT TABLE_NAME AS table_name, COLUMN_NAME AS column_name, COLUMN_TYPE AS column_type,
+	IS_NULLABLE = 'NO' AS not_null, COLUMN_DEFAULT AS column_default,
+	EXTRA LIKE '%auto_increment%' AS auto_increment, COLUMN_COMMENT AS column_comment
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY TABLE_NAME, ORDINAL_POSITION`).Scan(&columns).Error
+	if err != nil {
+		return nil, sderr.Wrap(err, "query mysql columns error")
+	}
+	var indexes []schemaDBIndex
+	err = tx.Raw(`
+SELECT TABLE_NAME AS table_name, INDEX_NAME AS index_name, INDEX_NAME = 'PRIMARY' AS "primary",
+	NON_UNIQUE = 0 AS "unique", COLUMN_NAME AS column_name
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE()
+ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`).Scan(&indexes).Error
+	if err != nil {
+		return nil, sderr.Wrap(err, "query mysql indexes error")
+	}
+	var fks []schemaDBForeignKey
+	err = tx.Raw(`
+SELECT TABLE_NAME AS table_name, CONSTRAINT_NAME AS const_name, COLUMN_NAME AS column_name,
+	REFERENCED_TABLE_NAME AS ref_table_name, REFERENCED_COLUMN_NAME AS ref_column_name
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`).Scan(&fks).Error
+	if err != nil {
+		return nil, sderr.Wrap(err, "query mysql foreign keys error")
+	}
+	return buildSchemaFromDB(DialectMysql, columns, indexes, fks), nil
+}
+
+func postgresSchemaFromDB(tx *gorm.DB) (*Schema, error) {
+	var columns []postgresDBColumn
+	err := tx.Raw(`
+SELECT c.table_name, c.column_name, c.data_type,
+	c.character_maximum_length AS char_len, c.numeric_precision AS num_prec, c.numeric_scale AS num_scale,
+	c.is_nullable = 'NO' AS not_null, c.column_default,
+	(c.is_identity = 'YES' OR COALESCE(c.column_default, '') LIKE 'nextval(%') AS auto_increment
+FROM information_schema.columns c
+JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE'
+ORDER BY c.table_name, c.ordinal_position`).Scan(&columns).Error
+	if err != nil {
+		return nil, sderr.Wrap(err, "query postgres columns error")
+	}
+	var indexes []schemaDBIndex
+	err = tx.Raw(`
+SELECT t.relname AS table_name, i.relname AS index_name, ix.indisprimary AS "primary",
+	ix.indisunique AS "unique", a.attname AS column_name
+FROM pg_index ix
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = current_schema()
+ORDER BY t.relname, i.relname, k.ord`).Scan(&indexes).Error
+	if err != nil {
+		return nil, sderr.Wrap(err, "query postgres indexes error")
+	}
+	var fks []schemaDBForeignKey
+	err = tx.Raw(`
+SELECT t.relname AS table_name, c.conname AS const_name, a.attname AS column_name,
+	rt.relname AS ref_table_name, ra.attname AS ref_column_name
+FROM pg_constraint c
+JOIN pg_class t ON t.oid = c.conrelid
+JOIN pg_class rt ON rt.oid = c.confrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, ref_attnum, ord)
+JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.ref_attnum
+WHERE c.contype = 'f' AND n.nspname = current_schema()
+ORDER BY t.relname, c.conname, k.ord`).Scan(&fks).Error
+	if err != nil {
+		return nil, sderr.Wrap(err, "query postgres foreign keys error")
+	}
+	columns1 := lo.Map(columns, func(c postgresDBColumn, _ int) schemaDBColumn {
+		col := c.schemaDBColumn
+		switch {
+		case (c.DataType == "character varying" || c.DataType == "character") && c.CharLen.Valid:
+			col.ColumnType = fmt.Sprintf("%s(%d)", c.DataType, c.CharLen.Int64)
+		case c.DataType == "numeric" && c.NumPrec.Valid:
+			col.ColumnType = fmt.Sprintf("numeric(%d,%d)", c.NumPrec.Int64, c.NumScale.Int64)
+		default:
+			col.ColumnType = c.DataType
+		}
+		if col.AutoIncrement {
+			// 自增列的默认值为nextval(...)或者identity
+			col.ColumnDefault = sql.NullString{}
+		}
+		return col
+	})
+	return buildSchemaFromDB(DialectPostgres, columns1, indexes, fks), nil
+}
+
+func buildSchemaFromDB(dialect string, columns []schemaDBColumn, indexes []schemaDBIndex, fks []schemaDBForeignKey) *Schema {
+	schema := &Schema{Dialect: dialect}
+	ensureTable := func(name string) *SchemaTable {
+		t := schema.Table(name)
+		if t == nil {
+			t = &SchemaTable{Name: name}
+			schema.Tables = append(schema.Tables, t)
+		}
+		return t
+	}
+	for _, c := range columns {
+		t := ensureTable(c.TableName)
+		t.Columns = append(t.Columns, &SchemaColumn{
+			Name:          c.ColumnName,
+			Type:          c.ColumnType,
+			NotNull:       c.NotNull,
+			Default:       dbDefaultLiteral(dialect, c.ColumnType, c.ColumnDefault),
+			AutoIncrement: c.AutoIncrement,
+			Comment:       c.ColumnComment,
+		})
+	}
+	for _, idx := range indexes {
+		t := schema.Table(idx.TableName)
+		if t == nil {
+			continue
+		}
+		if idx.Primary {
+			t.PrimaryKey = append(t.PrimaryKey, idx.ColumnName)
+			continue
+		}
+		si := lo.FindOrElse(t.Indexes, nil, func(si *SchemaIndex) bool { return si.Name == idx.IndexName })
+		if si == nil {
+			si = &SchemaIndex{Name: idx.IndexName, Unique: idx.Unique}
+			t.Indexes = append(t.Indexes, si)
+		}
+		si.Columns = append(si.Columns, idx.ColumnName)
+	}
+	for _, fk := range fks {
+		t := schema.Table(fk.TableName)
+		if t == nil {
+			continue
+		}
+		sfk := lo.FindOrElse(t.ForeignKeys, nil, func(sfk *SchemaForeignKey) bool { return sfk.Name == fk.ConstName })
+		if sfk == nil {
+			sfk = &SchemaForeignKey{Name: fk.ConstName, RefTable: fk.RefTableName}
+			t.ForeignKeys = append(t.ForeignKeys, sfk)
+		}
+		sfk.Columns = append(sfk.Columns, fk.ColumnName)
+		sfk.RefColumns = append(sfk.RefColumns, fk.RefColumnName)
+	}
+	return schema
+}
+
+func dbDefaultLiteral(dialect, columnType string, def sql.NullString) string {
+	if !def.Valid {
+		return ""
+	}
+	v := def.String
+	if dialect != DialectMysql || strings.HasPrefix(v, "'") || strings.EqualFold(v, "null") {
+		// postgres的column_default与MariaDB的COLUMN_DEFAULT本身就是SQL字面量
+		return v
+	}
+	typ := strings.ToLower(columnType)
+	for _, prefix := range []string{"char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set"} {
+		if strings.HasPrefix(typ, prefix) {
+			return ddlSingleQuote(v)
+		}
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package sdblueprint
+
+import (
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdjson"
+	"github.com/samber/lo"
+	"reflect"
+)
+
+// Snapshot 返回表结构的快照(ToJsonObject的JSON)，用于之后使用LoadSnapshot比较差异
+func (bp *Blueprint) Snapshot() []byte {
+	return []byte(sdjson.MarshalPretty(bp.ToJsonObject()))
+}
+
+// mergeSnapshot 只迁移了部分表时使用，返回prev中的表并将tableIds中的表替换为bp中的定义，prev中没有的表追加在最后
+func mergeSnapshot(prev, bp *Blueprint, tableIds []string) *Blueprint {
+	merged := &Blueprint{finalized: true}
+	if prev != nil {
+		for _, t := range prev.tables {
+			if !lo.Contains(tableIds, t.id) {
+				merged.tables = append(merged.tables, t)
+			} else if t1, ok := lo.Find(bp.tables, func(t1 *table) bool { return t1.id == t.id }); ok {
+				merged.tables = append(merged.tables, t1)
+			}
+		}
+	}
+	for _, t := range bp.tables {
+		if lo.Contains(tableIds, t.id) && !lo.ContainsBy(merged.tables, func(t1 *table) bool { return t1.id == t.id }) {
+			merged.tables = append(merged.tables, t)
+		}
+	}
+	return merged
+}
+
+type snapshotDoc struct {
+	Tables []struct {
+		Id         string            `json:"id"`
+		Comment    string            `json:"comment"`
+		Group      string            `json:"group"`
+		Attributes map[string]string `json:"attributes"`
+		Columns    []struct {
+			Id         string            `json:"id"`
+			Comment    string            `json:"comment"`
+			Type       string            `json:"type"`
+			Attributes map[string]string `json:"attributes"`
+		} `json:"columns"`
+		Indexes []struct {
+			Name             string   `json:"name"`
+			Comment          string   `json:"comment"`
+			Kind             string   `json:"kind"`
+			Columns          []string `json:"columns"`
+			ReferenceTable   string   `json:"reference_table"`
+			ReferenceColumns []string `json:"reference_columns"`
+			Order            string   `json:"order"`
+		} `json:"indexes"`
+	} `json:"tables"`
+}
+
+var snapshotColumnTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, v := range []any{
+		"", false, []byte(nil),
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+	} {
+		typ := reflect.TypeOf(v)
+		snapshotColumnTypes[typ.String()] = typ
+	}
+}
+
+// LoadSnapshot 从Snapshot的结果中恢复表结构，恢复后的Blueprint只包含表，没有query和module
+func LoadSnapshot(data []byte) (*Blueprint, error) {
+	doc, err := sdjson.UnmarshalTyped[snapshotDoc](data)
+	if err != nil {
+		return nil, sderr.Wrap(err, "unmarshal blueprint snapshot error")
+	}
+	bp := &Blueprint{finalized: true}
+	for _, t := range doc.Tables {
+		newTable := bp.addTable(t.Id, attributes(t.Attributes).ensure()).setComment(t.Comment).setGroup(t.Group)
+		for _, c := range t.Columns {
+			typ, ok := snapshotColumnTypes[c.Type]
+			if !ok {
+				return nil, sderr.NewWith("illegal column type in blueprint snapshot", sderr.Attrs{"t": t.Id, "c": c.Id, "type": c.Type})
+			}
+			newTable.addFieldAsColumn(&field{
+				id:         c.Id,
+				comment:    c.Comment,
+				typ:        typ,
+				attributes: attributes(c.Attributes).ensure(),
+			})
+		}
+		for _, idx := range t.Indexes {
+			newTable.indexes = append(newTable.indexes, &index{
+				name:             idx.Name,
+				comment:          idx.Comment,
+				kind:             IndexKind(idx.Kind),
+				columns:          idx.Columns,
+				referenceTable:   idx.ReferenceTable,
+				referenceColumns: idx.ReferenceColumns,
+				order:            IndexOrder(idx.Order),
+			})
+		}
+	}
+	return bp, nil
+}
@@ -436,6 +436,7 @@ func (idx *index) ToJsonObject() sdjson.Object {
 		return nil
 	}
 	return sdjson.Object{
+		"name":              idx.name,
 		"comment":           idx.comment,
 		"kind":              idx.kind,
 		"columns":           idx.columns,
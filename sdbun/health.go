@@ -0,0 +1,14 @@
+package sdbun
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/uptrace/bun"
+)
+
+// HealthCheck 返回ping数据库的检查函数，可以用于sdecho.Health
+func HealthCheck(db *bun.DB) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return sderr.Wrap(db.PingContext(ctx), "ping bun error")
+	}
+}
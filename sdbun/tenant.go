@@ -0,0 +1,63 @@
+package sdbun
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdsql"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+// TenantModel 嵌入到模型中，对select/update/delete自动添加tenant_id过滤，insert时自动填充tenant_id
+// 租户ID来自sdsql.WithTenant设置的ctx；ctx中没有租户时查询会返回sdsql.ErrNoTenant
+type TenantModel struct {
+	TenantID string `bun:"tenant_id,notnull"`
+}
+
+var (
+	_ bun.BeforeSelectHook         = (*TenantModel)(nil)
+	_ bun.BeforeUpdateHook         = (*TenantModel)(nil)
+	_ bun.BeforeDeleteHook         = (*TenantModel)(nil)
+	_ schema.BeforeAppendModelHook = (*TenantModel)(nil)
+)
+
+func (m *TenantModel) BeforeSelect(ctx context.Context, q *bun.SelectQuery) error {
+	return tenantWhere(ctx, func(tenant string) { q.Where("?TableAlias.tenant_id = ?", tenant) })
+}
+
+func (m *TenantModel) BeforeUpdate(ctx context.Context, q *bun.UpdateQuery) error {
+	return tenantWhere(ctx, func(tenant string) { q.Where("?TableAlias.tenant_id = ?", tenant) })
+}
+
+func (m *TenantModel) BeforeDelete(ctx context.Context, q *bun.DeleteQuery) error {
+	return tenantWhere(ctx, func(tenant string) { q.Where("?TableAlias.tenant_id = ?", tenant) })
+}
+
+func (m *TenantModel) BeforeAppendModel(ctx context.Context, q bun.Query) error {
+	if _, ok := q.(*bun.InsertQuery); !ok {
+		return nil
+	}
+	tenant, apply, err := sdsql.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
+	if !apply {
+		return nil
+	}
+	if m.TenantID != "" && m.TenantID != tenant {
+		return sderr.NewWith("tenant mismatch", m.TenantID)
+	}
+	m.TenantID = tenant
+	return nil
+}
+
+func tenantWhere(ctx context.Context, where func(tenant string)) error {
+	tenant, apply, err := sdsql.TenantFilter(ctx)
+	if err != nil {
+		return err
+	}
+	if apply {
+		where(tenant)
+	}
+	return nil
+}
@@ -14,6 +14,12 @@ type Cache interface {
 	GetOrPut(ctx context.Context, k any, loader func(ctx context.Context, k any) (any, error), opts *PutOptions) (any, error)
 }
 
+// Adder 可选接口，原子地写入不存在的key，用于实现分布式环境下的互斥标记
+type Adder interface {
+	// Add 只有k不存在时才写入v，返回是否写入
+	Add(ctx context.Context, k, v any, opts *PutOptions) (bool, error)
+}
+
 type PutOptions struct {
 	TTL  time.Duration
 	Cost int64
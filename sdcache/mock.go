@@ -15,7 +15,10 @@ type mockCache struct {
 	ttl   time.Duration
 }
 
-var _ Cache = &mockCache{}
+var (
+	_ Cache = &mockCache{}
+	_ Adder = &mockCache{}
+)
 
 func newMockCache(ttl time.Duration) *mockCache {
 	if ttl < 0 {
@@ -81,6 +84,13 @@ func (m *mockCache) Put(ctx context.Context, k, v any, opts *PutOptions) error {
 	return nil
 }
 
+func (m *mockCache) Add(ctx context.Context, k, v any, opts *PutOptions) (bool, error) {
+	if _, err := m.Get(ctx, k); err == nil {
+		return false, nil
+	}
+	return true, m.Put(ctx, k, v, opts)
+}
+
 func (m *mockCache) Delete(ctx context.Context, k any) error {
 	k1 := k.(string)
 	delete(m.cache, k1)
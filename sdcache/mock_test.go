@@ -10,4 +10,5 @@ func TestMock(t *testing.T) {
 	c := newMockCache(sdtime.Seconds(ttlSecs))
 	DoTestCommon(t, c)
 	DoTestExpiration(t, c, ttlSecs)
+	DoTestAdd(t, c)
 }
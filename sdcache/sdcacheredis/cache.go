@@ -20,7 +20,10 @@ type Config struct {
 	TTL     time.Duration
 }
 
-var _ sdcache.Cache = &Cache{}
+var (
+	_ sdcache.Cache = &Cache{}
+	_ sdcache.Adder = &Cache{}
+)
 
 func New(client redis.UniversalClient, config Config) (*Cache, error) {
 	if client == nil {
@@ -162,6 +165,27 @@ func (c *Cache) Put(ctx context.Context, k, v any, opts *sdcache.PutOptions) err
 	return nil
 }
 
+// Add 使用SETNX写入
+func (c *Cache) Add(ctx context.Context, k, v any, opts *sdcache.PutOptions) (bool, error) {
+	if err := c.checkConfig(true, true); err != nil {
+		return false, err
+	}
+	client, key, encoder := c.client, c.config.Key, c.config.Encoder
+	redisKey, err := key.EncodeKey(k)
+	if err != nil {
+		return false, sderr.Wrap(err, "encode redis key error")
+	}
+	redisVal, err := encoder.EncodeValue(k, v)
+	if err != nil {
+		return false, sderr.Wrap(err, "encode redis value error")
+	}
+	added, err := client.SetNX(ctx, redisKey, redisVal, c.getTTL(opts)).Result()
+	if err != nil {
+		return false, sderr.Wrap(err, "setnx redis value error")
+	}
+	return added, nil
+}
+
 func (c *Cache) Delete(ctx context.Context, k any) error {
 	if err := c.checkConfig(true, false); err != nil {
 		return err
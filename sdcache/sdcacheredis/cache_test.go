@@ -23,4 +23,5 @@ func TestCache(t *testing.T) {
 	// go
 	sdcache.DoTestCommon(t, c)
 	sdcache.DoTestExpiration(t, c, ttlSecs)
+	sdcache.DoTestAdd(t, c)
 }
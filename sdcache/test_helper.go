@@ -125,3 +125,18 @@ func DoTestExpiration(t *testing.T, c Cache, ttlSecs int64) {
 	assert.Equal(t, "v1", v1)
 	assert.Equal(t, 4, loadCounter)
 }
+
+func DoTestAdd(t *testing.T, c Adder) {
+	added, err := c.Add(context.Background(), "k_add", "v1", nil)
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = c.Add(context.Background(), "k_add", "v2", nil)
+	assert.NoError(t, err)
+	assert.False(t, added)
+	if c1, ok := c.(Cache); ok {
+		v, err := c1.Get(context.Background(), "k_add")
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", v)
+		assert.NoError(t, c1.Delete(context.Background(), "k_add"))
+	}
+}
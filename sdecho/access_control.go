@@ -47,6 +47,31 @@ func O(id string, tags ...string) Object {
 	}
 }
 
+// ParseObject 解析Object.String()的结果，例如"user.edit[admin|ops]"
+func ParseObject(s string) (Object, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Object{}, nil
+	}
+	id, tagsText := s, ""
+	if i := strings.Index(s, "["); i >= 0 {
+		if !strings.HasSuffix(s, "]") {
+			return Object{}, sderr.NewWith("illegal object", s)
+		}
+		id, tagsText = s[:i], s[i+1:len(s)-1]
+	}
+	tags := sdstrings.SplitNonempty(tagsText, "|", true)
+	if !pattObjectPart.MatchString(strings.TrimSpace(id)) {
+		return Object{}, sderr.NewWith("illegal object", s)
+	}
+	for _, tag := range tags {
+		if !pattObjectPart.MatchString(tag) {
+			return Object{}, sderr.NewWith("illegal object", s)
+		}
+	}
+	return O(id, tags...), nil
+}
+
 func (o Object) Id() string {
 	return o.id
 }
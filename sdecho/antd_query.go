@@ -0,0 +1,379 @@
+package sdecho
+
+import (
+	"context"
+	"fmt"
+	"github.com/gaorx/stardust5/sdbun"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdgorm"
+	"github.com/gaorx/stardust5/sdsql"
+	"github.com/uptrace/bun"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"slices"
+	"strings"
+)
+
+const (
+	AntdOpEq    = "eq"
+	AntdOpIn    = "in"
+	AntdOpLike  = "like"
+	AntdOpRange = "range"
+)
+
+// AntdQuery 将AntdJsonRequest中的params/sort/filter翻译为数据库查询条件
+// 只有在Columns中声明的字段才能参与排序和过滤
+type AntdQuery struct {
+	Columns         map[string]AntdColumn // key为ProTable中的字段名(dataIndex)
+	Keyword         []string              // keyword在这些列上进行like查询，多列之间为OR
+	DefaultSort     []AntdOrder
+	DefaultPageSize int
+	MaxPageSize     int // 请求的pageSize超过此值时使用此值，默认为200
+}
+
+type AntdColumn struct {
+	Column   string // 数据库中的列名，默认为字段名
+	Sortable bool
+	Filter   string // 过滤操作符，为空则不能过滤
+}
+
+type AntdCond struct {
+	Column string
+	Op     string
+	Args   []any
+}
+
+type AntdOrder struct {
+	Column string
+	Desc   bool
+}
+
+type AntdConds struct {
+	Request *AntdJsonRequest
+	Where   []AntdCond
+	Keyword []AntdCond // 多个条件之间为OR
+	Orders  []AntdOrder
+	Page    sdsql.Page
+}
+
+const (
+	defaultAntdPageSize    = 20
+	defaultAntdMaxPageSize = 200
+)
+
+func (q AntdQuery) Parse(req *AntdJsonRequest) (*AntdConds, error) {
+	if req == nil {
+		req = &AntdJsonRequest{}
+	}
+	defaultPageSize := q.DefaultPageSize
+	if defaultPageSize <= 0 {
+		defaultPageSize = defaultAntdPageSize
+	}
+	maxPageSize := q.MaxPageSize
+	if maxPageSize <= 0 {
+		maxPageSize = defaultAntdMaxPageSize
+	}
+	reqPage := req.PageDef(defaultPageSize)
+	reqPage.PageSize = min(reqPage.PageSize, maxPageSize)
+	conds := &AntdConds{
+		Request: req,
+		Page:    sdsql.Page1(reqPage.Page, reqPage.PageSize),
+	}
+
+	// where
+	fields := make([]string, 0, len(q.Columns))
+	for field := range q.Columns {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		col := q.Columns[field]
+		if col.Filter == "" {
+			continue
+		}
+		v, ok := req.Filter[field]
+		if !ok || v == nil {
+			v, ok = req.Params[field]
+		}
+		if !ok || v == nil {
+			continue
+		}
+		cond, ok, err := col.cond(field, v)
+		if err != nil {
+			return nil, sderr.Wrap(ErrBadRequest, err.Error())
+		}
+		if ok {
+			conds.Where = append(conds.Where, cond)
+		}
+	}
+
+	// keyword
+	if keyword := strings.TrimSpace(req.Keyword()); keyword != "" {
+		for _, column := range q.Keyword {
+			conds.Keyword = append(conds.Keyword, AntdCond{
+				Column: column,
+				Op:     AntdOpLike,
+				Args:   []any{antdLikePattern(keyword)},
+			})
+		}
+	}
+
+	// order
+	sortFields := make([]string, 0, len(req.Sort))
+	for field := range req.Sort {
+		sortFields = append(sortFields, field)
+	}
+	slices.Sort(sortFields)
+	for _, field := range sortFields {
+		col, ok := q.Columns[field]
+		if !ok || !col.Sortable {
+			continue
+		}
+		switch req.Sort.Get(field).AsStringDef("") {
+		case "ascend", "asc":
+			conds.Orders = append(conds.Orders, AntdOrder{Column: col.columnOf(field)})
+		case "descend", "desc":
+			conds.Orders = append(conds.Orders, AntdOrder{Column: col.columnOf(field), Desc: true})
+		}
+	}
+	if len(conds.Orders) <= 0 {
+		conds.Orders = slices.Clone(q.DefaultSort)
+	}
+	return conds, nil
+}
+
+func (col AntdColumn) columnOf(field string) string {
+	if col.Column != "" {
+		return col.Column
+	}
+	return field
+}
+
+func (col AntdColumn) cond(field string, v any) (AntdCond, bool, error) {
+	column := col.columnOf(field)
+	arr, isArr := v.([]any)
+	switch col.Filter {
+	case AntdOpEq:
+		if isArr {
+			if len(arr) <= 0 {
+				return AntdCond{}, false, nil
+			}
+			v = arr[0]
+		}
+		if s, ok := v.(string); ok && s == "" {
+			return AntdCond{}, false, nil
+		}
+		return AntdCond{Column: column, Op: AntdOpEq, Args: []any{v}}, true, nil
+	case AntdOpIn:
+		if !isArr {
+			arr = []any{v}
+		}
+		if len(arr) <= 0 {
+			return AntdCond{}, false, nil
+		}
+		return AntdCond{Column: column, Op: AntdOpIn, Args: arr}, true, nil
+	case AntdOpLike:
+		s := strings.TrimSpace(fmt.Sprintf("%v", v))
+		if s == "" {
+			return AntdCond{}, false, nil
+		}
+		return AntdCond{Column: column, Op: AntdOpLike, Args: []any{antdLikePattern(s)}}, true, nil
+	case AntdOpRange:
+		if !isArr || len(arr) != 2 {
+			return AntdCond{}, false, sderr.NewWith("illegal range filter", field)
+		}
+		if isEmptyRangeBound(arr[0]) && isEmptyRangeBound(arr[1]) {
+			return AntdCond{}, false, nil
+		}
+		return AntdCond{Column: column, Op: AntdOpRange, Args: arr}, true, nil
+	default:
+		return AntdCond{}, false, sderr.NewWith("illegal filter operator", col.Filter)
+	}
+}
+
+// gorm
+
+func (conds *AntdConds) GormWhereScope() func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		var exprs []clause.Expression
+		for _, cond := range conds.Where {
+			exprs = append(exprs, cond.gormExpr())
+		}
+		if len(conds.Keyword) > 0 {
+			var keywordExprs []clause.Expression
+			for _, cond := range conds.Keyword {
+				keywordExprs = append(keywordExprs, cond.gormExpr())
+			}
+			exprs = append(exprs, clause.Or(keywordExprs...))
+		}
+		if len(exprs) <= 0 {
+			return tx
+		}
+		return tx.Clauses(clause.Where{Exprs: exprs})
+	}
+}
+
+func (conds *AntdConds) GormOrderScope() func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		for _, order := range conds.Orders {
+			tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: order.Column}, Desc: order.Desc})
+		}
+		return tx
+	}
+}
+
+func (conds *AntdConds) GormScope() func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Scopes(conds.GormWhereScope(), conds.GormOrderScope())
+	}
+}
+
+func (cond AntdCond) gormExpr() clause.Expression {
+	column := clause.Column{Name: cond.Column}
+	switch cond.Op {
+	case AntdOpIn:
+		return clause.IN{Column: column, Values: cond.Args}
+	case AntdOpLike:
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '" + antdLikeEscape + "'", Vars: []any{column, cond.Args[0]}}
+	case AntdOpRange:
+		var exprs []clause.Expression
+		if !isEmptyRangeBound(cond.Args[0]) {
+			exprs = append(exprs, clause.Gte{Column: column, Value: cond.Args[0]})
+		}
+		if !isEmptyRangeBound(cond.Args[1]) {
+			exprs = append(exprs, clause.Lte{Column: column, Value: cond.Args[1]})
+		}
+		return clause.And(exprs...)
+	default:
+		return clause.Eq{Column: column, Value: cond.Args[0]}
+	}
+}
+
+// FindGorm builder返回的gorm.DB不应包含order、limit和offset
+func FindGorm[T any](builder func() *gorm.DB, conds *AntdConds) (*FindResult[T], error) {
+	var rows []T
+	dbr := builder().Scopes(conds.GormScope(), sdgorm.PageScope(conds.Page)).Find(&rows)
+	if dbr.Error != nil {
+		return nil, sderr.WithStack(dbr.Error)
+	}
+	var numRows int64
+	dbr = builder().Scopes(conds.GormWhereScope()).Count(&numRows)
+	if dbr.Error != nil {
+		return nil, sderr.WithStack(dbr.Error)
+	}
+	limit, _ := conds.Page.LimitOffset()
+	return &FindResult[T]{
+		Data:      rows,
+		Request:   conds.Request,
+		NumRows:   int(numRows),
+		PageSize:  limit,
+		PageNum:   conds.Page.TrimNum(),
+		PageTotal: (int(numRows) + limit - 1) / limit,
+	}, nil
+}
+
+// bun
+
+func (conds *AntdConds) BunWhereApplier() func(*bun.SelectQuery) *bun.SelectQuery {
+	return func(q *bun.SelectQuery) *bun.SelectQuery {
+		for _, cond := range conds.Where {
+			q = cond.bunWhere(q, false)
+		}
+		if len(conds.Keyword) > 0 {
+			q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				for _, cond := range conds.Keyword {
+					q = cond.bunWhere(q, true)
+				}
+				return q
+			})
+		}
+		return q
+	}
+}
+
+func (conds *AntdConds) BunOrderApplier() func(*bun.SelectQuery) *bun.SelectQuery {
+	return func(q *bun.SelectQuery) *bun.SelectQuery {
+		for _, order := range conds.Orders {
+			if order.Desc {
+				q = q.OrderExpr("? DESC", bun.Ident(order.Column))
+			} else {
+				q = q.OrderExpr("? ASC", bun.Ident(order.Column))
+			}
+		}
+		return q
+	}
+}
+
+func (conds *AntdConds) BunApplier() func(*bun.SelectQuery) *bun.SelectQuery {
+	return func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Apply(conds.BunWhereApplier()).Apply(conds.BunOrderApplier())
+	}
+}
+
+func (cond AntdCond) bunWhere(q *bun.SelectQuery, or bool) *bun.SelectQuery {
+	where := q.Where
+	if or {
+		where = q.WhereOr
+	}
+	column := bun.Ident(cond.Column)
+	switch cond.Op {
+	case AntdOpIn:
+		return where("? IN (?)", column, bun.In(cond.Args))
+	case AntdOpLike:
+		return where("? LIKE ? ESCAPE '"+antdLikeEscape+"'", column, cond.Args[0])
+	case AntdOpRange:
+		lower, upper := cond.Args[0], cond.Args[1]
+		switch {
+		case isEmptyRangeBound(lower):
+			return where("? <= ?", column, upper)
+		case isEmptyRangeBound(upper):
+			return where("? >= ?", column, lower)
+		default:
+			return where("? BETWEEN ? AND ?", column, lower, upper)
+		}
+	default:
+		return where("? = ?", column, cond.Args[0])
+	}
+}
+
+func FindBun[T any](ctx context.Context, db bun.IDB, qfn func(*bun.SelectQuery) *bun.SelectQuery, conds *AntdConds) (*FindResult[T], error) {
+	pr, err := sdbun.SelectPage[T](ctx, db, conds.Page, func(q *bun.SelectQuery) *bun.SelectQuery {
+		if qfn != nil {
+			q = qfn(q)
+		}
+		return q.Apply(conds.BunApplier())
+	})
+	if err != nil {
+		return nil, sderr.WithStack(err)
+	}
+	return FindResultOf(pr, conds.Request), nil
+}
+
+func FindResultOf[T any](pr *sdsql.PagingResult[T], req any) *FindResult[T] {
+	return &FindResult[T]{
+		Data:      pr.Rows,
+		Request:   req,
+		NumRows:   pr.NumRows,
+		PageSize:  pr.PageSize,
+		PageNum:   pr.PageNum,
+		PageTotal: pr.PageTotal,
+	}
+}
+
+// 使用!作为LIKE的转义字符，避免反斜杠在mysql字符串中也需要转义的问题
+const antdLikeEscape = "!"
+
+var antdLikeEscaper = strings.NewReplacer(antdLikeEscape, antdLikeEscape+antdLikeEscape, "%", antdLikeEscape+"%", "_", antdLikeEscape+"_")
+
+// antdLikePattern 转义s中的%和_，生成包含s的LIKE模式，需要配合ESCAPE使用
+func antdLikePattern(s string) string {
+	return "%" + antdLikeEscaper.Replace(s) + "%"
+}
+
+func isEmptyRangeBound(v any) bool {
+	if v == nil {
+		return true
+	}
+	s, ok := v.(string)
+	return ok && s == ""
+}
@@ -0,0 +1,126 @@
+package sdecho
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sdconcur"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdjson"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/uptrace/bun"
+	"gorm.io/gorm"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+const (
+	defaultAuditTable = "audit_events"
+)
+
+// slog
+
+type AuditSlogSink struct {
+	Logger *slog.Logger // 为nil时使用slog.Default()
+	Level  slog.Level
+}
+
+func (sink AuditSlogSink) Write(ctx context.Context, events []*AuditEvent) error {
+	l := sdslog.L(sink.Logger)
+	for _, event := range events {
+		l.LogAttrs(ctx, sink.Level, "audit",
+			sdslog.Time("at", event.At),
+			sdslog.String("uid", event.UID),
+			sdslog.String("object", event.Object),
+			sdslog.String("action", event.Action),
+			sdslog.String("method", event.Method),
+			sdslog.String("path", event.Path),
+			sdslog.String("remote_ip", event.RemoteIP),
+			sdslog.Bool("allowed", event.Allowed),
+			sdslog.String("error", event.Error),
+			sdslog.Int("status", event.Status),
+			sdslog.Float64("latency", event.Latency),
+		)
+	}
+	return nil
+}
+
+// gorm
+
+type AuditGormSink struct {
+	DB    *gorm.DB
+	Table string // 默认为audit_events
+}
+
+func (sink AuditGormSink) Write(ctx context.Context, events []*AuditEvent) error {
+	table := sink.Table
+	if table == "" {
+		table = defaultAuditTable
+	}
+	dbr := sink.DB.WithContext(ctx).Table(table).Create(events)
+	if dbr.Error != nil {
+		return sderr.Wrap(dbr.Error, "insert audit events error (gorm)")
+	}
+	return nil
+}
+
+// bun
+
+type AuditBunSink struct {
+	DB    bun.IDB
+	Table string // 默认为audit_events
+}
+
+func (sink AuditBunSink) Write(ctx context.Context, events []*AuditEvent) error {
+	table := sink.Table
+	if table == "" {
+		table = defaultAuditTable
+	}
+	_, err := sink.DB.NewInsert().Model(&events).ModelTableExpr("?", bun.Ident(table)).Exec(ctx)
+	if err != nil {
+		return sderr.Wrap(err, "insert audit events error (bun)")
+	}
+	return nil
+}
+
+// json lines file
+
+type AuditFileSink struct {
+	mtx sync.Mutex
+	f   *os.File
+}
+
+func NewAuditFileSink(filename string) (*AuditFileSink, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, sderr.WrapWith(err, "open audit file error", filename)
+	}
+	return &AuditFileSink{f: f}, nil
+}
+
+func (sink *AuditFileSink) Write(_ context.Context, events []*AuditEvent) error {
+	var lines []byte
+	for _, event := range events {
+		line, err := sdjson.Marshal(event)
+		if err != nil {
+			return sderr.Wrap(err, "marshal audit event error")
+		}
+		lines = append(lines, line...)
+		lines = append(lines, '\n')
+	}
+	var err error
+	sdconcur.Lock(&sink.mtx, func() {
+		_, err = sink.f.Write(lines)
+	})
+	if err != nil {
+		return sderr.Wrap(err, "write audit file error")
+	}
+	return nil
+}
+
+func (sink *AuditFileSink) Close() error {
+	var err error
+	sdconcur.Lock(&sink.mtx, func() {
+		err = sink.f.Close()
+	})
+	return err
+}
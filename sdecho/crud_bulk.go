@@ -0,0 +1,364 @@
+package sdecho
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/gaorx/stardust5/sdcsv"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdjson"
+	"github.com/gaorx/stardust5/sdreflect"
+	"github.com/gaorx/stardust5/sdstrings"
+	"github.com/labstack/echo/v4"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	BulkFormatCsv   = "csv"
+	BulkFormatJson  = "json"
+	BulkFormatJsonl = "jsonl"
+)
+
+type BatchRow[T any] struct {
+	Index int    `json:"index"`
+	Data  T      `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func batchEach[T, R any](rows []T, f func(T) (R, error)) *Result {
+	results := make([]BatchRow[R], 0, len(rows))
+	numFailed := 0
+	for i, row := range rows {
+		r, err := f(row)
+		if err != nil {
+			numFailed++
+			results = append(results, BatchRow[R]{Index: i, Error: err.Error()})
+		} else {
+			results = append(results, BatchRow[R]{Index: i, Data: r})
+		}
+	}
+	return ResultOk(results).WithFields(map[string]any{
+		"succeeded": len(rows) - numFailed,
+		"failed":    numFailed,
+	})
+}
+
+// import
+
+// importRows 先读取并解码全部行，读取出错或者行数超过maxRows时返回ErrBadRequest且不调用f，然后逐行校验并调用f
+func importRows[T any](ec echo.Context, maxRows int, f func(T) (T, error)) *Result {
+	r, format, closer, err := importReader(ec)
+	if err != nil {
+		return ResultErr(sderr.Wrap(ErrBadRequest, err.Error()))
+	}
+	defer func() {
+		if closer != nil {
+			_ = closer.Close()
+		}
+	}()
+
+	rows, rowErrs, err := decodeImportRows[T](r, format, maxRows)
+	if err != nil {
+		return ResultErr(err)
+	}
+	results := make([]BatchRow[T], 0, len(rows))
+	numFailed := 0
+	for i, row := range rows {
+		err := rowErrs[i]
+		if err == nil {
+			err = validateRequest(row)
+		}
+		if err == nil {
+			row, err = f(row)
+		}
+		if err != nil {
+			numFailed++
+			results = append(results, BatchRow[T]{Index: i, Error: err.Error()})
+		} else {
+			results = append(results, BatchRow[T]{Index: i, Data: row})
+		}
+	}
+	return ResultOk(results).WithFields(map[string]any{
+		"succeeded": len(results) - numFailed,
+		"failed":    numFailed,
+	})
+}
+
+// decodeImportRows 返回解码后的行以及每一行的解码错误，数据流本身的错误或者行数超过maxRows时返回ErrBadRequest
+func decodeImportRows[T any](r io.Reader, format string, maxRows int) ([]T, []error, error) {
+	var rows []T
+	var rowErrs []error
+	add := func(row T, err error) error {
+		if len(rows) >= maxRows {
+			return sderr.Wrap(ErrBadRequest, "too many rows")
+		}
+		rows = append(rows, row)
+		rowErrs = append(rowErrs, err)
+		return nil
+	}
+
+	switch format {
+	case BulkFormatCsv:
+		csvReader, err := sdcsv.NewReader(r, &sdcsv.Options{Header: true, TrimLeadingSpace: true})
+		if err != nil {
+			return nil, nil, sderr.Wrap(ErrBadRequest, err.Error())
+		}
+		typ := sdreflect.T[T]()
+		var addErr error
+		err = csvReader.ForeachMap(func(_ int, rec map[string]string) sdcsv.HandlerResult {
+			row, err := csvRecordTo[T](typ, rec)
+			if addErr = add(row, err); addErr != nil {
+				return sdcsv.Stop
+			}
+			return sdcsv.Continue
+		})
+		if err != nil {
+			return nil, nil, sderr.Wrap(ErrBadRequest, err.Error())
+		}
+		if addErr != nil {
+			return nil, nil, addErr
+		}
+	case BulkFormatJson:
+		dec := json.NewDecoder(r)
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return nil, nil, sderr.Wrap(ErrBadRequest, "illegal json array")
+		}
+		for dec.More() {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, nil, sderr.Wrap(ErrBadRequest, err.Error())
+			}
+			row := newAsPtr[T]()
+			if err := add(row, json.Unmarshal(raw, &row)); err != nil {
+				return nil, nil, err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, nil, sderr.Wrap(ErrBadRequest, err.Error())
+		}
+	case BulkFormatJsonl:
+		dec := json.NewDecoder(r)
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, nil, sderr.Wrap(ErrBadRequest, err.Error())
+			}
+			row := newAsPtr[T]()
+			if err := add(row, json.Unmarshal(raw, &row)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return rows, rowErrs, nil
+}
+
+func importReader(ec echo.Context) (io.Reader, string, io.Closer, error) {
+	req := ec.Request()
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if mediaType == echo.MIMEMultipartForm {
+		fh, err := ec.FormFile("file")
+		if err != nil {
+			return nil, "", nil, sderr.Wrap(err, "get import file error")
+		}
+		format := bulkFormatOf(ec.FormValue("_format"), "", path.Ext(fh.Filename))
+		f, err := fh.Open()
+		if err != nil {
+			return nil, "", nil, sderr.Wrap(err, "open import file error")
+		}
+		return f, format, f, nil
+	}
+	return req.Body, bulkFormatOf(ec.QueryParam("_format"), mediaType, ""), nil, nil
+}
+
+func bulkFormatOf(format, mediaType, ext string) string {
+	switch strings.ToLower(format) {
+	case BulkFormatCsv, BulkFormatJson, BulkFormatJsonl:
+		return strings.ToLower(format)
+	}
+	switch {
+	case mediaType == "text/csv" || ext == ".csv":
+		return BulkFormatCsv
+	case mediaType == "application/x-ndjson" || mediaType == "application/jsonl" || ext == ".jsonl" || ext == ".ndjson":
+		return BulkFormatJsonl
+	default:
+		return BulkFormatJson
+	}
+}
+
+var tTimeForCsv = sdreflect.T[time.Time]()
+
+func csvRecordTo[T any](typ reflect.Type, rec map[string]string) (T, error) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	o := map[string]json.RawMessage{}
+	if typ.Kind() == reflect.Struct {
+		for _, f := range structFieldsByTag(typ, "json") {
+			cell, ok := rec[f.name]
+			if !ok {
+				continue
+			}
+			fieldTyp := f.typ
+			for fieldTyp.Kind() == reflect.Pointer {
+				fieldTyp = fieldTyp.Elem()
+			}
+			if fieldTyp.Kind() == reflect.String {
+				cell = csvUnescapeCell(cell)
+			}
+			if fieldTyp.Kind() == reflect.String || fieldTyp == tTimeForCsv || !json.Valid([]byte(cell)) {
+				if cell == "" && fieldTyp.Kind() != reflect.String {
+					continue
+				}
+				quoted, _ := json.Marshal(cell)
+				o[f.name] = quoted
+			} else {
+				o[f.name] = json.RawMessage(cell)
+			}
+		}
+	}
+	row := newAsPtr[T]()
+	raw, err := json.Marshal(o)
+	if err != nil {
+		return row, sderr.Wrap(err, "marshal csv record error")
+	}
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return row, sderr.Wrap(err, "unmarshal csv record error")
+	}
+	return row, nil
+}
+
+// export
+
+// exportRows 行数超过maxRows时返回ErrBadRequest
+func exportRows[T any](ec echo.Context, filename string, maxRows int, rows []T) error {
+	if len(rows) > maxRows {
+		return sderr.Wrap(ErrBadRequest, "too many rows")
+	}
+	format := bulkFormatOf(ec.QueryParam("_format"), "", "")
+	res := ec.Response()
+	switch format {
+	case BulkFormatCsv:
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	case BulkFormatJsonl:
+		res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	default:
+		res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	}
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+"."+format+`"`)
+	res.WriteHeader(http.StatusOK)
+
+	switch format {
+	case BulkFormatCsv:
+		columns := csvColumnsOf(sdreflect.T[T]())
+		w := csv.NewWriter(res)
+		if err := w.Write(columns); err != nil {
+			return sderr.Wrap(err, "write csv header error")
+		}
+		for _, row := range rows {
+			o, err := sdjson.StructToObject(row)
+			if err != nil {
+				return sderr.WithStack(err)
+			}
+			rec := make([]string, 0, len(columns))
+			for _, column := range columns {
+				rec = append(rec, csvCellOf(o[column]))
+			}
+			if err := w.Write(rec); err != nil {
+				return sderr.Wrap(err, "write csv record error")
+			}
+			w.Flush()
+			res.Flush()
+		}
+		w.Flush()
+		return sderr.Wrap(w.Error(), "write csv error")
+	case BulkFormatJsonl:
+		enc := json.NewEncoder(res)
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return sderr.Wrap(err, "write jsonl error")
+			}
+			res.Flush()
+		}
+		return nil
+	default:
+		if _, err := res.Write([]byte("[")); err != nil {
+			return sderr.Wrap(err, "write json error")
+		}
+		for i, row := range rows {
+			raw, err := json.Marshal(row)
+			if err != nil {
+				return sderr.Wrap(err, "marshal json error")
+			}
+			if i > 0 {
+				raw = append([]byte(","), raw...)
+			}
+			if _, err := res.Write(raw); err != nil {
+				return sderr.Wrap(err, "write json error")
+			}
+			res.Flush()
+		}
+		_, err := res.Write([]byte("]"))
+		return sderr.Wrap(err, "write json error")
+	}
+}
+
+func csvColumnsOf(typ reflect.Type) []string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	var columns []string
+	for _, f := range structFieldsByTag(typ, "json") {
+		columns = append(columns, f.name)
+	}
+	return columns
+}
+
+func csvCellOf(v any) string {
+	switch v1 := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return csvEscapeCell(v1)
+	default:
+		return sdjson.MarshalStringDef(v1, "")
+	}
+}
+
+// csvEscapeCell 在以=、+、-、@等开头的字符串前加上单引号，防止在电子表格中作为公式执行
+func csvEscapeCell(s string) string {
+	if csvIsFormulaLike(strings.TrimLeft(s, "'")) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvUnescapeCell csvEscapeCell的逆操作
+func csvUnescapeCell(s string) string {
+	if strings.HasPrefix(s, "'") && csvIsFormulaLike(strings.TrimLeft(s[1:], "'")) {
+		return s[1:]
+	}
+	return s
+}
+
+func csvIsFormulaLike(s string) bool {
+	return s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0]))
+}
+
+func exportFilenameOf(p string) string {
+	segments := sdstrings.SplitNonempty(p, "/", true)
+	if len(segments) <= 0 {
+		return "export"
+	}
+	return segments[len(segments)-1]
+}
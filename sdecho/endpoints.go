@@ -8,7 +8,6 @@ import (
 	"github.com/gaorx/stardust5/sdstrings"
 	"github.com/gaorx/stardust5/sdurl"
 	"github.com/gaorx/stardust5/sdvalidator"
-	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/samber/lo"
 	"net/http"
@@ -18,14 +17,28 @@ import (
 
 // endpoint
 
+var injectedArgTypes = []reflect.Type{
+	sdreflect.T[echo.Context](),
+	sdreflect.T[Context](),
+	sdreflect.T[Token](),
+	sdreflect.T[*Token](),
+}
+
 type Endpoint struct {
 	Methods     []string
 	Path        string
 	Object      Object
 	Bare        bool // 跳过decode_token和access_control流程，直接调用func
+	Idempotent  bool // 安装Idempotency后，根据Idempotency-Key重放结果
+	Cached      bool // 安装ResponseCache后，缓存成功的结果，只应用于只读的endpoint
 	Func        any
 	Middlewares []echo.MiddlewareFunc
+	Summary     string       // 用于生成文档
+	DataType    reflect.Type // Result中data的类型，用于生成文档
 	handler     echo.HandlerFunc
+	page        bool
+	requestType reflect.Type
+	fieldTypes  map[string]reflect.Type
 }
 
 type WebPage struct {
@@ -33,16 +46,22 @@ type WebPage struct {
 	Path        string
 	Object      Object
 	Bare        bool
+	Cached      bool
 	Func        any
 	Middlewares []echo.MiddlewareFunc
+	Summary     string
 }
 
 type API struct {
 	Path        string
 	Object      Object
 	Bare        bool
+	Idempotent  bool
+	Cached      bool
 	Func        any
 	Middlewares []echo.MiddlewareFunc
+	Summary     string
+	DataType    reflect.Type
 }
 
 type RecordID interface{ ~string | ~int | ~int64 }
@@ -66,6 +85,7 @@ type ListAPI[T Record[ID], ID RecordID, REQ BaseRequest] struct {
 	Bare        bool
 	Func        func(echo.Context, REQ) ([]T, error)
 	Middlewares []echo.MiddlewareFunc
+	Summary     string
 }
 
 type FindAPI[T Record[ID], ID RecordID, REQ BaseRequest] struct {
@@ -74,6 +94,7 @@ type FindAPI[T Record[ID], ID RecordID, REQ BaseRequest] struct {
 	Bare        bool
 	Func        func(echo.Context, REQ) (*FindResult[T], error)
 	Middlewares []echo.MiddlewareFunc
+	Summary     string
 }
 
 type CrudAPI[T Record[ID], ID RecordID, REQ BaseRequest] struct {
@@ -88,6 +109,21 @@ type CrudAPI[T Record[ID], ID RecordID, REQ BaseRequest] struct {
 	ObjectR     Object
 	ObjectW     Object
 	Middlewares []echo.MiddlewareFunc
+	Summary     string
+	Batch       bool // 生成batch_create/batch_update/batch_delete，逐行调用Create/Update/Delete
+	Import      bool // 生成import，支持csv/json/jsonl，逐行调用Create
+	Export      bool // 生成export，支持csv/json/jsonl，使用List获取数据
+	Idempotent  bool // create/update以及batch_create/batch_update支持Idempotency-Key
+	MaxRows     int  // batch和import一次最多处理的行数，默认1000
+	MaxExport   int  // export一次最多导出的行数，默认10000
+}
+
+var findResultFieldTypes = map[string]reflect.Type{
+	"request":   sdreflect.TAny,
+	"page":      sdreflect.TInt,
+	"pageSize":  sdreflect.TInt,
+	"pageTotal": sdreflect.TInt,
+	"numRows":   sdreflect.TInt,
 }
 
 func (fr *FindResult[T]) ToResult(err error) *Result {
@@ -109,8 +145,11 @@ func (p WebPage) ToEndpoint() Endpoint {
 		Path:        p.Path,
 		Object:      p.Object,
 		Bare:        p.Bare,
+		Cached:      p.Cached,
 		Func:        p.Func,
 		Middlewares: p.Middlewares,
+		Summary:     p.Summary,
+		page:        true,
 	}
 }
 
@@ -120,20 +159,23 @@ func (api API) ToEndpoint() Endpoint {
 		Path:        api.Path,
 		Object:      api.Object,
 		Bare:        api.Bare,
+		Idempotent:  api.Idempotent,
+		Cached:      api.Cached,
 		Func:        api.Func,
 		Middlewares: api.Middlewares,
+		Summary:     api.Summary,
+		DataType:    api.DataType,
 	}
 }
 
 func (api ListAPI[T, ID, REQ]) ToEndpoint() Endpoint {
-	return API{
+	endpoint := API{
 		Path:   api.Path,
 		Object: api.Object,
 		Func: func(ec Context) *Result {
 			var req = newAsPtr[REQ]()
-			err := ec.Bind(&req)
-			if err != nil {
-				return ResultErr(ErrBadRequest, "parse request error")
+			if err := bindRequest(ec, &req); err != nil {
+				return ResultErr(err)
 			}
 			flagsText := ec.QueryParam("_flags")
 			if flagsText != "" {
@@ -144,18 +186,21 @@ func (api ListAPI[T, ID, REQ]) ToEndpoint() Endpoint {
 		},
 		Bare:        api.Bare,
 		Middlewares: api.Middlewares,
+		Summary:     api.Summary,
+		DataType:    sdreflect.T[[]T](),
 	}.ToEndpoint()
+	endpoint.requestType = sdreflect.T[REQ]()
+	return endpoint
 }
 
 func (api FindAPI[T, ID, REQ]) ToEndpoint() Endpoint {
-	return API{
+	endpoint := API{
 		Path:   api.Path,
 		Object: api.Object,
 		Func: func(ec Context) *Result {
 			var req = newAsPtr[REQ]()
-			err := ec.Bind(&req)
-			if err != nil {
-				return ResultErr(ErrBadRequest, "parse request error")
+			if err := bindRequest(ec, &req); err != nil {
+				return ResultErr(err)
 			}
 			flagsText := ec.QueryParam("_flags")
 			if flagsText != "" {
@@ -166,7 +211,26 @@ func (api FindAPI[T, ID, REQ]) ToEndpoint() Endpoint {
 		},
 		Bare:        api.Bare,
 		Middlewares: api.Middlewares,
+		Summary:     api.Summary,
+		DataType:    sdreflect.T[[]T](),
 	}.ToEndpoint()
+	endpoint.requestType = sdreflect.T[REQ]()
+	endpoint.fieldTypes = findResultFieldTypes
+	return endpoint
+}
+
+func (api CrudAPI[T, ID, REQ]) maxRows() int {
+	if api.MaxRows <= 0 {
+		return 1000
+	}
+	return api.MaxRows
+}
+
+func (api CrudAPI[T, ID, REQ]) maxExport() int {
+	if api.MaxExport <= 0 {
+		return 10000
+	}
+	return api.MaxExport
 }
 
 func (api CrudAPI[T, ID, REQ]) ToEndpoints() []Endpoint {
@@ -176,14 +240,21 @@ func (api CrudAPI[T, ID, REQ]) ToEndpoints() []Endpoint {
 		}
 		return second
 	}
+	summaryOf := func(op string) string {
+		if api.Summary == "" {
+			return ""
+		}
+		return api.Summary + " " + op
+	}
 
 	var endpoints []Endpoint
 
 	// create
 	if api.Create != nil {
 		endpoints = append(endpoints, API{
-			Path:   sdurl.JoinPath(api.Path, "create"),
-			Object: selectObject(api.ObjectW, api.Object),
+			Path:       sdurl.JoinPath(api.Path, "create"),
+			Object:     selectObject(api.ObjectW, api.Object),
+			Idempotent: api.Idempotent,
 			Func: func(ec echo.Context, entityReq T) *Result {
 				var req = newAsPtr[REQ]()
 				req.SetFlags(sdstrings.SplitNonempty(ec.QueryParam("_flags"), ",", true))
@@ -191,14 +262,17 @@ func (api CrudAPI[T, ID, REQ]) ToEndpoints() []Endpoint {
 				return ResultOf(created, err)
 			},
 			Middlewares: api.Middlewares,
+			Summary:     summaryOf("create"),
+			DataType:    sdreflect.T[T](),
 		}.ToEndpoint())
 	}
 
 	// update
 	if api.Update != nil {
 		endpoints = append(endpoints, API{
-			Path:   sdurl.JoinPath(api.Path, "update"),
-			Object: selectObject(api.ObjectW, api.Object),
+			Path:       sdurl.JoinPath(api.Path, "update"),
+			Object:     selectObject(api.ObjectW, api.Object),
+			Idempotent: api.Idempotent,
 			Func: func(ec echo.Context, entityReq T) *Result {
 				var req = newAsPtr[REQ]()
 				req.SetFlags(sdstrings.SplitNonempty(ec.QueryParam("_flags"), ",", true))
@@ -207,6 +281,8 @@ func (api CrudAPI[T, ID, REQ]) ToEndpoints() []Endpoint {
 				return ResultOf(updated, err)
 			},
 			Middlewares: api.Middlewares,
+			Summary:     summaryOf("update"),
+			DataType:    sdreflect.T[T](),
 		}.ToEndpoint())
 	}
 
@@ -223,6 +299,8 @@ func (api CrudAPI[T, ID, REQ]) ToEndpoints() []Endpoint {
 				return ResultOf("deleted", err)
 			},
 			Middlewares: api.Middlewares,
+			Summary:     summaryOf("delete"),
+			DataType:    sdreflect.T[string](),
 		}.ToEndpoint())
 	}
 
@@ -240,6 +318,8 @@ func (api CrudAPI[T, ID, REQ]) ToEndpoints() []Endpoint {
 				return ResultOf(record, err)
 			},
 			Middlewares: api.Middlewares,
+			Summary:     summaryOf("get"),
+			DataType:    sdreflect.T[T](),
 		}.ToEndpoint())
 	}
 
@@ -251,6 +331,7 @@ func (api CrudAPI[T, ID, REQ]) ToEndpoints() []Endpoint {
 			Bare:        false,
 			Func:        api.List,
 			Middlewares: api.Middlewares,
+			Summary:     summaryOf("list"),
 		}.ToEndpoint())
 	}
 
@@ -262,9 +343,139 @@ func (api CrudAPI[T, ID, REQ]) ToEndpoints() []Endpoint {
 			Bare:        false,
 			Func:        api.Find,
 			Middlewares: api.Middlewares,
+			Summary:     summaryOf("find"),
 		}.ToEndpoint())
 	}
 
+	// batch
+	if api.Batch && api.Create != nil {
+		endpoints = append(endpoints, API{
+			Path:       sdurl.JoinPath(api.Path, "batch_create"),
+			Object:     selectObject(api.ObjectW, api.Object),
+			Idempotent: api.Idempotent,
+			Func: func(ec echo.Context, rowsReq struct {
+				Rows []T `json:"rows"`
+			}) *Result {
+				if len(rowsReq.Rows) > api.maxRows() {
+					return ResultErr(sderr.Wrap(ErrBadRequest, "too many rows"))
+				}
+				var req = newAsPtr[REQ]()
+				req.SetFlags(sdstrings.SplitNonempty(ec.QueryParam("_flags"), ",", true))
+				return batchEach(rowsReq.Rows, func(row T) (T, error) {
+					if err := validateRequest(row); err != nil {
+						return row, err
+					}
+					return api.Create(ec, row, req)
+				})
+			},
+			Middlewares: api.Middlewares,
+			Summary:     summaryOf("batch create"),
+			DataType:    sdreflect.T[[]BatchRow[T]](),
+		}.ToEndpoint())
+	}
+	if api.Batch && api.Update != nil {
+		endpoints = append(endpoints, API{
+			Path:       sdurl.JoinPath(api.Path, "batch_update"),
+			Object:     selectObject(api.ObjectW, api.Object),
+			Idempotent: api.Idempotent,
+			Func: func(ec echo.Context, rowsReq struct {
+				Rows []T `json:"rows"`
+			}) *Result {
+				if len(rowsReq.Rows) > api.maxRows() {
+					return ResultErr(sderr.Wrap(ErrBadRequest, "too many rows"))
+				}
+				var req = newAsPtr[REQ]()
+				req.SetFlags(sdstrings.SplitNonempty(ec.QueryParam("_flags"), ",", true))
+				req.SetFields(sdstrings.SplitNonempty(ec.QueryParam("_fields"), ",", true))
+				return batchEach(rowsReq.Rows, func(row T) (T, error) {
+					if err := validateRequest(row); err != nil {
+						return row, err
+					}
+					return api.Update(ec, row, req)
+				})
+			},
+			Middlewares: api.Middlewares,
+			Summary:     summaryOf("batch update"),
+			DataType:    sdreflect.T[[]BatchRow[T]](),
+		}.ToEndpoint())
+	}
+	if api.Batch && api.Delete != nil {
+		endpoints = append(endpoints, API{
+			Path:   sdurl.JoinPath(api.Path, "batch_delete"),
+			Object: selectObject(api.ObjectW, api.Object),
+			Func: func(ec echo.Context, idsReq struct {
+				Ids []ID `json:"ids"`
+			}) *Result {
+				if len(idsReq.Ids) > api.maxRows() {
+					return ResultErr(sderr.Wrap(ErrBadRequest, "too many rows"))
+				}
+				var req = newAsPtr[REQ]()
+				return batchEach(idsReq.Ids, func(id ID) (ID, error) {
+					return id, api.Delete(ec, id, req)
+				})
+			},
+			Middlewares: api.Middlewares,
+			Summary:     summaryOf("batch delete"),
+			DataType:    sdreflect.T[[]BatchRow[ID]](),
+		}.ToEndpoint())
+	}
+
+	// import
+	if api.Import && api.Create != nil {
+		endpoints = append(endpoints, API{
+			Path:   sdurl.JoinPath(api.Path, "import"),
+			Object: selectObject(api.ObjectW, api.Object),
+			Func: func(ec echo.Context) *Result {
+				var req = newAsPtr[REQ]()
+				req.SetFlags(sdstrings.SplitNonempty(ec.QueryParam("_flags"), ",", true))
+				return importRows(ec, api.maxRows(), func(row T) (T, error) {
+					return api.Create(ec, row, req)
+				})
+			},
+			Middlewares: api.Middlewares,
+			Summary:     summaryOf("import"),
+			DataType:    sdreflect.T[[]BatchRow[T]](),
+		}.ToEndpoint())
+	}
+
+	// export
+	if api.Export && api.List != nil {
+		object := selectObject(api.ObjectR, api.Object)
+		endpoints = append(endpoints, Endpoint{
+			Methods: []string{http.MethodGet, http.MethodPost},
+			Path:    sdurl.JoinPath(api.Path, "export"),
+			Object:  object,
+			// 直接写入响应流，需要自行执行endpointCheck
+			Func: func(ec echo.Context) error {
+				routes := MustGet[*Routes](ec, keyRoutes)
+				if _, err := endpointCheck(ec, object, false); err != nil {
+					return ResultErr(err).Write(ec, routes.ResultOptions)
+				}
+				var req = newAsPtr[REQ]()
+				if ec.Request().ContentLength > 0 {
+					if err := bindRequest(ec, &req); err != nil {
+						return ResultErr(err).Write(ec, routes.ResultOptions)
+					}
+				}
+				req.SetFlags(sdstrings.SplitNonempty(ec.QueryParam("_flags"), ",", true))
+				rows, err := api.List(ec, req)
+				if err != nil {
+					return ResultErr(err).Write(ec, routes.ResultOptions)
+				}
+				if err := exportRows(ec, exportFilenameOf(api.Path), api.maxExport(), rows); err != nil {
+					if !ec.Response().Committed {
+						return ResultErr(err).Write(ec, routes.ResultOptions)
+					}
+					return err
+				}
+				return nil
+			},
+			Middlewares: api.Middlewares,
+			Summary:     summaryOf("export"),
+			requestType: sdreflect.T[REQ](),
+		})
+	}
+
 	return endpoints
 }
 
@@ -304,17 +515,12 @@ func (endpoint *Endpoint) ToHandler() (echo.HandlerFunc, error) {
 	} else {
 		funcVal := sdreflect.ValueOf(endpoint.Func)
 		inTypes, outTypes := sdreflect.InOutTypes(funcVal.Type())
-		if len(outTypes) != 1 || outTypes[0] != sdreflect.T[*Result]() {
+		if len(outTypes) != 1 || (outTypes[0] != sdreflect.T[*Result]() && !isStreamType(outTypes[0])) {
 			return nil, sderr.NewWith("illegal result type", endpoint.Path)
 		}
 		numFreeParam := 0
 		for _, inType := range inTypes {
-			if !slices.Contains([]reflect.Type{
-				sdreflect.T[echo.Context](),
-				sdreflect.T[Context](),
-				sdreflect.T[Token](),
-				sdreflect.T[*Token](),
-			}, inType) {
+			if !slices.Contains(injectedArgTypes, inType) {
 				numFreeParam += 1
 			}
 		}
@@ -327,20 +533,48 @@ func (endpoint *Endpoint) ToHandler() (echo.HandlerFunc, error) {
 	}
 }
 
+func (endpoint *Endpoint) RequestType() reflect.Type {
+	if endpoint.requestType != nil {
+		return endpoint.requestType
+	}
+	if endpoint.Func == nil {
+		return nil
+	}
+	funcTyp := reflect.TypeOf(endpoint.Func)
+	if funcTyp.Kind() != reflect.Func {
+		return nil
+	}
+	for _, inType := range sdreflect.InTypes(funcTyp) {
+		if !slices.Contains(injectedArgTypes, inType) {
+			return inType
+		}
+	}
+	return nil
+}
+
 func (endpoint *Endpoint) renderDefault(ec echo.Context, funcVal reflect.Value, inTypes []reflect.Type) error {
 	routes := MustGet[*Routes](ec, keyRoutes)
-	var token Token
-	if !endpoint.Bare {
-		token0, err := TokenDecode(context.Background(), ec)
-		if err != nil {
-			return ResultErr(err).Write(ec, routes.ResultOptions)
+	token, err := endpointCheck(ec, endpoint.Object, endpoint.Bare)
+	if err != nil {
+		return ResultErr(err).Write(ec, routes.ResultOptions)
+	}
+	call := func() *Result {
+		return endpoint.callDefault(ec, token, funcVal, inTypes)
+	}
+	if endpoint.Cached {
+		if rc, ok := Get[*responseCache](ec, keyResponseCache); ok {
+			return rc.serve(ec, token, routes.ResultOptions, call)
 		}
-		err = AccessControlCheck(context.Background(), ec, token0, endpoint.Object, ActionCall)
-		if err != nil {
-			return ResultErr(err).Write(ec, routes.ResultOptions)
+	}
+	if endpoint.Idempotent {
+		if idem, ok := Get[*idempotency](ec, keyIdempotency); ok {
+			return idem.serve(ec, token, routes.ResultOptions, call)
 		}
-		token = token0
 	}
+	return call().Write(ec, routes.ResultOptions)
+}
+
+func (endpoint *Endpoint) callDefault(ec echo.Context, token Token, funcVal reflect.Value, inTypes []reflect.Type) *Result {
 	var inVals, outVals []reflect.Value
 	for _, inTyp := range inTypes {
 		switch inTyp {
@@ -360,17 +594,8 @@ func (endpoint *Endpoint) renderDefault(ec echo.Context, funcVal reflect.Value,
 			} else {
 				reqPtr = reflect.New(inTyp).Interface()
 			}
-			if err := ec.Bind(reqPtr); err != nil {
-				return ResultErr(sderr.Wrap(ErrBadRequest, err.Error())).Write(ec, routes.ResultOptions)
-			}
-			if isStructOrStructPtr(inTyp) {
-				if err := sdvalidator.Struct(reqPtr); err != nil {
-					if _, ok := sderr.AsT[validator.ValidationErrors](err); ok {
-						return ResultErr(sderr.Wrap(ErrBadRequest, "validate error")).Write(ec, routes.ResultOptions)
-					} else {
-						return ResultErr(sderr.Wrap(ErrBadRequest, err.Error())).Write(ec, routes.ResultOptions)
-					}
-				}
+			if err := bindRequest(ec, reqPtr); err != nil {
+				return ResultErr(err)
 			}
 			if reqIsPtr {
 				inVals = append(inVals, reflect.ValueOf(reqPtr))
@@ -383,13 +608,79 @@ func (endpoint *Endpoint) renderDefault(ec echo.Context, funcVal reflect.Value,
 		outVals = funcVal.Call(inVals)
 	}); !ok {
 		sdslog.WithAttr("path", endpoint.Path).Error("call endpoint error")
-		return ResultErr(sderr.WithStack(ErrInternalServerError)).Write(ec, routes.ResultOptions)
+		return ResultErr(sderr.WithStack(ErrInternalServerError))
+	}
+	var res *Result
+	if isStreamType(outVals[0].Type()) {
+		// 直接返回chan或迭代器，根据Accept选择SSE或NDJSON
+		res = ResultStream(Stream{Format: streamFormatOf(ec), Source: outVals[0].Interface()})
+	} else {
+		res = outVals[0].Interface().(*Result)
 	}
-	res := outVals[0].Interface().(*Result)
 	if res == nil {
 		res = ResultOk(nil)
 	}
-	return res.Write(ec, routes.ResultOptions)
+	return res
+}
+
+// endpointCheck 执行decode_token、tenant、access_control和rate_limit流程
+func endpointCheck(ec echo.Context, object Object, bare bool) (Token, error) {
+	var token Token
+	if !bare {
+		token0, err := TokenDecode(context.Background(), ec)
+		if err != nil {
+			return Token{}, err
+		}
+		if err := tenantCheck(ec, token0); err != nil {
+			return Token{}, err
+		}
+		err = AccessControlCheck(context.Background(), ec, token0, object, ActionCall)
+		if err != nil {
+			return Token{}, err
+		}
+		token = token0
+	} else {
+		if err := tenantCheck(ec, token); err != nil {
+			return Token{}, err
+		}
+	}
+	if err := RateLimitCheck(context.Background(), ec, token, object); err != nil {
+		return Token{}, err
+	}
+	return token, nil
+}
+
+// bindRequest 绑定请求参数，如果是结构体则进行校验，校验错误会在Result中展开为fields
+func bindRequest(ec echo.Context, reqPtr any) error {
+	if err := ec.Bind(reqPtr); err != nil {
+		return sderr.Wrap(ErrBadRequest, err.Error())
+	}
+	return validateRequest(reflect.ValueOf(reqPtr).Elem().Interface())
+}
+
+// validateRequest 如果req是结构体或者结构体指针则进行校验，batch和import中逐行使用
+func validateRequest(req any) error {
+	reqVal := reflect.ValueOf(req)
+	if !reqVal.IsValid() || !isStructOrStructPtr(reqVal.Type()) {
+		return nil
+	}
+	if reqVal.Kind() == reflect.Ptr {
+		if reqVal.IsNil() {
+			return nil
+		}
+	} else {
+		// Struct需要指针或者结构体值，复制为指针以便与原来的行为一致
+		ptr := reflect.New(reqVal.Type())
+		ptr.Elem().Set(reqVal)
+		req = ptr.Interface()
+	}
+	if err := sdvalidator.JSON().Struct(req); err != nil {
+		if sdvalidator.FieldsErrors(err) != nil {
+			return sderr.WithStack(err)
+		}
+		return sderr.Wrap(ErrBadRequest, err.Error())
+	}
+	return nil
 }
 
 func isStructOrStructPtr(typ reflect.Type) bool {
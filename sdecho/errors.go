@@ -11,5 +11,8 @@ var (
 	ErrInternalServerError = sderr.Sentinel("internal server error")
 	ErrDecodeToken         = sderr.Sentinel("decode token error")
 	ErrTokenExpired        = sderr.Sentinel("token expired")
+	ErrTokenRevoked        = sderr.Sentinel("token revoked")
 	ErrLogin               = sderr.Sentinel("login error")
+	ErrTooManyRequests     = sderr.Sentinel("too many requests")
+	ErrConflict            = sderr.Sentinel("conflict")
 )
@@ -58,7 +58,14 @@ func (ac AccessControl) Apply(app *echo.Echo) error {
 
 func AccessControlCheck(ctx context.Context, ec echo.Context, token Token, object Object, action string) error {
 	checker := MustGet[accessControlChecker](ec, keyAccessControlChecker)
-	defaultObjectVars := MustGet[map[string]string](ec, keyAccessControlObjectVars)
-	object1 := object.Expand(contextExpandMapper(ec), defaultObjectVars)
-	return checker(ctx, ec, token, object1, action)
+	object1 := expandObject(ec, object)
+	err := checker(ctx, ec, token, object1, action)
+	auditRecord(ec, token, object1, action, err)
+	return err
+}
+
+func expandObject(ec echo.Context, object Object) Object {
+	defaultObjectVars, _ := Get[map[string]string](ec, keyAccessControlObjectVars)
+	// ${tenant}优先使用解析出的租户，不能被请求参数覆盖
+	return object.Expand(tenantObjectVars(ec), contextExpandMapper(ec), defaultObjectVars)
 }
@@ -0,0 +1,188 @@
+package sdecho
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/gaorx/stardust5/sdtime"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+type AuditEvent struct {
+	At       time.Time `json:"at"`
+	UID      string    `json:"uid"`
+	Object   string    `json:"object"`
+	Action   string    `json:"action"`
+	Method   string    `json:"method"`
+	Path     string    `json:"path"`
+	RemoteIP string    `json:"remote_ip"`
+	Allowed  bool      `json:"allowed"`
+	Error    string    `json:"error,omitempty"`
+	Status   int       `json:"status"`
+	Latency  float64   `json:"latency"` // 请求的耗时，毫秒
+}
+
+type AuditSink interface {
+	Write(ctx context.Context, events []*AuditEvent) error
+}
+
+// Audit 记录access control的检查结果，事件通过队列异步写入Sinks，不阻塞请求，
+// 队列满时丢弃事件，停止时(Echo.Run)等待队列中的事件写入完成
+type Audit struct {
+	Sinks     []AuditSink
+	Actions   []string // 需要记录的action，默认只记录ActionCall
+	Skipper   func(echo.Context) bool
+	QueueSize int // 队列中最多缓存的请求数，默认为1024
+}
+
+const (
+	keyAuditEvents = "sdecho.audit_events"
+)
+
+const (
+	defaultAuditQueueSize = 1024
+)
+
+type auditEvents struct {
+	actions []string
+	events  []*AuditEvent
+}
+
+func (a Audit) Apply(app *echo.Echo) error {
+	if len(a.Sinks) <= 0 {
+		return sderr.New("no audit sinks")
+	}
+	actions := a.Actions
+	if len(actions) <= 0 {
+		actions = []string{ActionCall}
+	}
+	if a.QueueSize <= 0 {
+		a.QueueSize = defaultAuditQueueSize
+	}
+	q := newAuditQueue(a.Sinks, a.QueueSize)
+	lifecycleOf(app).add("audit", q.close)
+
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			if a.Skipper != nil && a.Skipper(ec) {
+				return next(ec)
+			}
+			startAt := time.Now()
+			collected := &auditEvents{actions: actions}
+			ec.Set(keyAuditEvents, collected)
+			err := next(ec)
+			if len(collected.events) > 0 {
+				latency := sdtime.ToMillisF(time.Since(startAt))
+				status := auditStatusOf(ec, err)
+				for _, event := range collected.events {
+					event.Status = status
+					event.Latency = latency
+				}
+				q.push(collected.events)
+			}
+			return err
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+// auditStatusOf 响应还没有写入时(错误交给echo的HTTPErrorHandler处理)，根据err得到状态码
+func auditStatusOf(ec echo.Context, err error) int {
+	if ec.Response().Committed || err == nil {
+		return ec.Response().Status
+	}
+	if httpErr, ok := sderr.AsT[*echo.HTTPError](err); ok {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
+
+func auditRecord(ec echo.Context, token Token, object Object, action string, err error) {
+	collected, ok := Get[*auditEvents](ec, keyAuditEvents)
+	if !ok || !slices.Contains(collected.actions, action) {
+		return
+	}
+	event := &AuditEvent{
+		At:       time.Now(),
+		UID:      token.UID,
+		Object:   object.String(),
+		Action:   action,
+		Method:   ec.Request().Method,
+		Path:     ec.Path(),
+		RemoteIP: ec.RealIP(),
+		Allowed:  err == nil,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	collected.events = append(collected.events, event)
+}
+
+// queue
+
+type auditQueue struct {
+	sinks  []AuditSink
+	mtx    sync.RWMutex
+	closed bool
+	ch     chan []*AuditEvent
+	done   chan struct{}
+}
+
+func newAuditQueue(sinks []AuditSink, size int) *auditQueue {
+	q := &auditQueue{
+		sinks: sinks,
+		ch:    make(chan []*AuditEvent, size),
+		done:  make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *auditQueue) push(events []*AuditEvent) {
+	q.mtx.RLock()
+	defer q.mtx.RUnlock()
+	if q.closed {
+		sdslog.Error("audit queue closed, drop events")
+		return
+	}
+	select {
+	case q.ch <- events:
+	default:
+		sdslog.Error("audit queue full, drop events")
+	}
+}
+
+func (q *auditQueue) run() {
+	defer close(q.done)
+	ctx := context.Background()
+	for events := range q.ch {
+		for _, sink := range q.sinks {
+			if sink == nil {
+				continue
+			}
+			if err := sink.Write(ctx, events); err != nil {
+				sdslog.WithError(err).Error("write audit events error")
+			}
+		}
+	}
+}
+
+func (q *auditQueue) close(ctx context.Context) error {
+	q.mtx.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+	q.mtx.Unlock()
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return sderr.Wrap(ctx.Err(), "wait audit queue error")
+	}
+}
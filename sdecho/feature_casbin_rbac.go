@@ -3,11 +3,13 @@ package sdecho
 import (
 	"context"
 	"github.com/gaorx/stardust5/sdcasbin"
+	"github.com/gaorx/stardust5/sderr"
 	"github.com/labstack/echo/v4"
 )
 
 type CasbinRbac struct {
 	Rbac              sdcasbin.Rbac
+	TenantRbac        func(tenant string) (sdcasbin.Rbac, error) // 安装Tenants后，每个租户作为一个domain使用各自的Rbac
 	CheckToken        func(echo.Context, Token) (bool, error)
 	DefaultObjectVars map[string]string
 }
@@ -24,10 +26,18 @@ func (ac CasbinRbac) Apply(app *echo.Echo) error {
 				}
 			}
 		}
-		if ac.Rbac == nil {
+		rbac := ac.Rbac
+		if tenant := TenantOf(ec); tenant != "" && ac.TenantRbac != nil {
+			rbac0, err := ac.TenantRbac(tenant)
+			if err != nil {
+				return false, sderr.WithStack(err)
+			}
+			rbac = rbac0
+		}
+		if rbac == nil {
 			return false, nil
 		}
-		ok := ac.Rbac.IsGranted(token.UID, object.String(), action)
+		ok := rbac.IsGranted(token.UID, object.String(), action)
 		return ok, nil
 	}
 	return AccessControl{
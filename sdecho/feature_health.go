@@ -0,0 +1,235 @@
+package sdecho
+
+import (
+	"context"
+	"fmt"
+	"github.com/gaorx/stardust5/sdconcur"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/exp/maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Health 提供/healthz、/readyz和Prometheus文本格式的/metrics
+// /healthz只要进程能响应就返回200；/readyz并发执行Checkers，全部成功返回200，否则返回503
+// /metrics统计每个Endpoint.Path和Result code的请求数和耗时
+// /readyz和/metrics默认是公开的，可以设置Object进行access control检查
+type Health struct {
+	Checkers     map[string]HealthChecker
+	Object       Object        // 不为空时/readyz和/metrics需要通过此Object的检查
+	Detail       bool          // /readyz是否返回checker的错误信息，默认失败时只返回fail
+	CheckTimeout time.Duration // 每次readiness检查的超时，默认为5s
+	HealthPath   string        // 默认为/healthz
+	ReadyPath    string        // 默认为/readyz
+	MetricsPath  string        // 默认为/metrics，为"-"时不统计metrics
+	Buckets      []float64     // 耗时直方图的bucket，单位为秒，默认为DefaultLatencyBuckets
+}
+
+// HealthChecker 返回nil表示就绪
+type HealthChecker func(ctx context.Context) error
+
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+const (
+	keyResultCode = "sdecho.result_code"
+	keyMetrics    = "sdecho.metrics"
+)
+
+func (h Health) Apply(app *echo.Echo) error {
+	if h.CheckTimeout <= 0 {
+		h.CheckTimeout = 5 * time.Second
+	}
+	if h.HealthPath == "" {
+		h.HealthPath = "/healthz"
+	}
+	if h.ReadyPath == "" {
+		h.ReadyPath = "/readyz"
+	}
+	if h.MetricsPath == "" {
+		h.MetricsPath = "/metrics"
+	}
+	if len(h.Buckets) <= 0 {
+		h.Buckets = DefaultLatencyBuckets
+	}
+
+	app.GET(h.HealthPath, func(ec echo.Context) error {
+		return ec.String(http.StatusOK, "ok")
+	})
+	app.GET(h.ReadyPath, h.guard(func(ec echo.Context) error {
+		checks, ready := h.check(ec.Request().Context())
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		return ec.JSON(status, map[string]any{"ready": ready, "checks": checks})
+	}))
+	if h.MetricsPath == "-" {
+		return nil
+	}
+
+	m := &metrics{buckets: slices.Clone(h.Buckets), series: map[string]*metricSeries{}}
+	sort.Float64s(m.buckets)
+	app.GET(h.MetricsPath, h.guard(func(ec echo.Context) error {
+		return ec.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", m.expose())
+	}))
+	skipped := []string{h.HealthPath, h.ReadyPath, h.MetricsPath}
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			if slices.Contains(skipped, ec.Path()) {
+				return next(ec)
+			}
+			ec.Set(keyMetrics, m)
+			startAt := time.Now()
+			err := next(ec)
+			m.observe(ec, err, time.Since(startAt))
+			return err
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+func (h *Health) guard(handler echo.HandlerFunc) echo.HandlerFunc {
+	if h.Object.IsEmpty() {
+		return handler
+	}
+	return func(ec echo.Context) error {
+		if _, err := endpointCheck(ec, h.Object, false); err != nil {
+			return ResultErr(err).Write(ec, resultOptionsOf(ec))
+		}
+		return handler(ec)
+	}
+}
+
+func (h *Health) check(ctx context.Context) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, h.CheckTimeout)
+	defer cancel()
+	names := maps.Keys(h.Checkers)
+	slices.Sort(names)
+	errs := make([]error, len(names))
+	_ = sdconcur.Do(0, names, func(i int, name string) {
+		if checker := h.Checkers[name]; checker != nil {
+			errs[i] = checker(ctx)
+		}
+	})
+	checks, ready := map[string]string{}, true
+	for i, name := range names {
+		if errs[i] != nil {
+			checks[name], ready = "fail", false
+			if h.Detail {
+				checks[name] = errs[i].Error()
+			}
+		} else {
+			checks[name] = "ok"
+		}
+	}
+	return checks, ready
+}
+
+// metrics
+
+type metrics struct {
+	mtx     sync.Mutex
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+var metricMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+type metricSeries struct {
+	method, path, code string
+	count              uint64
+	sum                float64
+	buckets            []uint64
+}
+
+func (m *metrics) observe(ec echo.Context, err error, elapsed time.Duration) {
+	// method和path会作为label，为了避免label过多，非标准的method和没有匹配路由的path分别归为OTHER和unmatched
+	method, path := ec.Request().Method, ec.Path()
+	if !slices.Contains(metricMethods, method) {
+		method = "OTHER"
+	}
+	if path == "" || err == echo.ErrNotFound || err == echo.ErrMethodNotAllowed {
+		path = "unmatched"
+	}
+	var code string
+	if resultCode, ok := Get[any](ec, keyResultCode); ok {
+		code = fmt.Sprint(resultCode)
+	} else {
+		status := ec.Response().Status
+		if err != nil {
+			status = http.StatusInternalServerError
+			if httpErr, ok := err.(*echo.HTTPError); ok {
+				status = httpErr.Code
+			}
+		}
+		code = strconv.Itoa(status)
+	}
+	seconds := elapsed.Seconds()
+	key := method + "\x00" + path + "\x00" + code
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	s, ok := m.series[key]
+	if !ok {
+		s = &metricSeries{method: method, path: path, code: code, buckets: make([]uint64, len(m.buckets))}
+		m.series[key] = s
+	}
+	s.count++
+	s.sum += seconds
+	for i, le := range m.buckets {
+		if seconds <= le {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (m *metrics) expose() []byte {
+	m.mtx.Lock()
+	series := make([]metricSeries, 0, len(m.series))
+	keys := maps.Keys(m.series)
+	slices.Sort(keys)
+	for _, key := range keys {
+		s := *m.series[key]
+		s.buckets = slices.Clone(s.buckets)
+		series = append(series, s)
+	}
+	m.mtx.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP sdecho_requests_total Total number of requests.\n")
+	b.WriteString("# TYPE sdecho_requests_total counter\n")
+	for _, s := range series {
+		fmt.Fprintf(&b, "sdecho_requests_total{%s} %d\n", s.labels(), s.count)
+	}
+	b.WriteString("# HELP sdecho_request_duration_seconds Request latency in seconds.\n")
+	b.WriteString("# TYPE sdecho_request_duration_seconds histogram\n")
+	for _, s := range series {
+		labels := s.labels()
+		for i, le := range m.buckets {
+			fmt.Fprintf(&b, "sdecho_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(le, 'g', -1, 64), s.buckets[i])
+		}
+		fmt.Fprintf(&b, "sdecho_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.count)
+		fmt.Fprintf(&b, "sdecho_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "sdecho_request_duration_seconds_count{%s} %d\n", labels, s.count)
+	}
+	return []byte(b.String())
+}
+
+func (s *metricSeries) labels() string {
+	return fmt.Sprintf(`method="%s",path="%s",code="%s"`, escapeLabel(s.method), escapeLabel(s.path), escapeLabel(s.code))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,109 @@
+package sdecho
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/language"
+)
+
+// I18n 为每个请求协商locale，依次使用query参数、cookie和Accept-Language，都不匹配时使用Default
+// 安装后Result中的错误消息、菜单名称和html模板中的t函数都会使用Catalog翻译
+type I18n struct {
+	Catalog    *Catalog
+	Locales    []string // 支持的locale，默认为Catalog中的所有locale
+	Default    string   // 默认为Locales中的第一个
+	QueryParam string   // 默认为_lang
+	Cookie     string   // 默认为lang
+}
+
+const (
+	keyI18n   = "sdecho.i18n"
+	keyLocale = "sdecho.locale"
+)
+
+func (i I18n) Apply(app *echo.Echo) error {
+	if i.Catalog == nil {
+		return sderr.New("no i18n catalog")
+	}
+	if len(i.Locales) <= 0 {
+		i.Locales = i.Catalog.Locales()
+	}
+	if len(i.Locales) <= 0 {
+		return sderr.New("no i18n locales")
+	}
+	if i.Default == "" {
+		i.Default = i.Locales[0]
+	}
+	if i.QueryParam == "" {
+		i.QueryParam = "_lang"
+	}
+	if i.Cookie == "" {
+		i.Cookie = "lang"
+	}
+	var tags []language.Tag
+	for _, locale := range i.Locales {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return sderr.WrapWith(err, "parse locale error", locale)
+		}
+		tags = append(tags, tag)
+	}
+	matcher := language.NewMatcher(tags)
+
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			ec.Set(keyI18n, &i)
+			ec.Set(keyLocale, i.negotiate(ec, matcher))
+			return next(ec)
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+func (i *I18n) negotiate(ec echo.Context, matcher language.Matcher) string {
+	var candidates []string
+	if lang := ec.QueryParam(i.QueryParam); lang != "" {
+		candidates = append(candidates, lang)
+	}
+	if cookie, err := ec.Cookie(i.Cookie); err == nil && cookie.Value != "" {
+		candidates = append(candidates, cookie.Value)
+	}
+	for _, candidate := range candidates {
+		if tag, err := language.Parse(candidate); err == nil {
+			if _, index, conf := matcher.Match(tag); conf != language.No {
+				return normalizeLocale(i.Locales[index])
+			}
+		}
+	}
+	if acceptLang := ec.Request().Header.Get("Accept-Language"); acceptLang != "" {
+		if tags, _, err := language.ParseAcceptLanguage(acceptLang); err == nil && len(tags) > 0 {
+			if _, index, conf := matcher.Match(tags...); conf != language.No {
+				return normalizeLocale(i.Locales[index])
+			}
+		}
+	}
+	return normalizeLocale(i.Default)
+}
+
+// Locale 安装I18n后返回协商的locale，否则依次使用_lang参数和Accept-Language
+func (c Context) Locale() string {
+	return requestLocale(c)
+}
+
+// T 使用I18n中的Catalog翻译，没有安装I18n时返回key
+func (c Context) T(key string, args ...any) string {
+	return translate(c, key, args...)
+}
+
+func translate(ec echo.Context, key string, args ...any) string {
+	i, ok := Get[*I18n](ec, keyI18n)
+	if !ok {
+		if len(args) > 0 {
+			return fmt.Sprintf(key, args...)
+		}
+		return key
+	}
+	return i.Catalog.Translate(requestLocale(ec), key, args...)
+}
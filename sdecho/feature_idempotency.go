@@ -0,0 +1,243 @@
+package sdecho
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gaorx/stardust5/sdcache"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/labstack/echo/v4"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotency-Replayed"
+)
+
+// Idempotency 对Idempotent的endpoint启用幂等，请求头中带有Idempotency-Key时，第一次成功的响应会在TTL内被重放
+// 相同key的请求仍在处理中，或者相同key的请求体不同时，返回ErrConflict
+// Cache中的值类型为IdempotencyRecord，使用sdcacheredis时可以使用sdcache.JsonEncoder[IdempotencyRecord]
+// Cache实现了sdcache.Adder(例如sdcacheredis)时，处理中的标记使用Add写入，多个进程之间也不会重复处理
+type Idempotency struct {
+	Cache      sdcache.Cache
+	TTL        time.Duration // 默认24小时
+	PendingTTL time.Duration // 处理中的标记的有效期，默认1分钟，应大于请求的最长处理时间
+	KeyPrefix  string
+}
+
+// IdempotencyRecord 保存在Cache中的记录，Pending为true表示请求仍在处理中
+type IdempotencyRecord struct {
+	Pending     bool   `json:"pending,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	HttpStatus  int    `json:"http_status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+const (
+	keyIdempotency = "sdecho.idempotency"
+)
+
+const (
+	maxIdempotencyKeyLen = 255
+	numIdempotencyLocks  = 64
+)
+
+type idempotency struct {
+	Idempotency
+	locks [numIdempotencyLocks]sync.Mutex
+}
+
+func (idem Idempotency) Apply(app *echo.Echo) error {
+	if idem.Cache == nil {
+		return sderr.New("no idempotency cache")
+	}
+	if idem.TTL <= 0 {
+		idem.TTL = 24 * time.Hour
+	}
+	if idem.PendingTTL <= 0 {
+		idem.PendingTTL = time.Minute
+	}
+	if idem.KeyPrefix == "" {
+		idem.KeyPrefix = "sdecho.idempotency."
+	}
+	i := &idempotency{Idempotency: idem}
+
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			ec.Set(keyIdempotency, i)
+			return next(ec)
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+// serve 如果请求中没有Idempotency-Key，直接调用call并写入结果
+func (i *idempotency) serve(ec echo.Context, token Token, opts *ResultOptions, call func() *Result) error {
+	key := ec.Request().Header.Get(HeaderIdempotencyKey)
+	if key == "" {
+		return call().Write(ec, opts)
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		return ResultErr(sderr.Wrap(ErrBadRequest, "idempotency key too long")).Write(ec, opts)
+	}
+	fingerprint, err := idempotencyFingerprint(ec)
+	if err != nil {
+		return ResultErr(err).Write(ec, opts)
+	}
+
+	ctx := context.Background()
+	cacheKey := i.KeyPrefix + url.QueryEscape(TenantOf(ec)) + ":" + url.QueryEscape(token.UID) + ":" + key
+	var replay *IdempotencyRecord
+	checkExisting := func(existing *IdempotencyRecord) error {
+		if existing.Fingerprint != fingerprint {
+			return sderr.Wrap(ErrConflict, "idempotency key reused with different request")
+		}
+		if existing.Pending {
+			return sderr.Wrap(ErrConflict, "idempotent request in progress")
+		}
+		replay = existing
+		return nil
+	}
+	err = i.lock(cacheKey, func() error {
+		existing, err := i.get(ctx, cacheKey)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return checkExisting(existing)
+		}
+		added, err := i.putPending(ctx, cacheKey, IdempotencyRecord{Pending: true, Fingerprint: fingerprint})
+		if err != nil {
+			return err
+		}
+		if !added {
+			// 其他进程先写入了记录
+			existing, err := i.get(ctx, cacheKey)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				return sderr.Wrap(ErrConflict, "idempotent request in progress")
+			}
+			return checkExisting(existing)
+		}
+		return nil
+	})
+	if err != nil {
+		return ResultErr(err).Write(ec, opts)
+	}
+	if replay != nil {
+		ec.Response().Header().Set(HeaderIdempotencyReplayed, "true")
+		return ec.Blob(replay.HttpStatus, replay.ContentType, replay.Body)
+	}
+
+	// 只保存成功的结果，失败时删除处理中的标记，客户端可以使用相同的key重试
+	res := call()
+	if res == nil {
+		res = ResultOk(nil)
+	}
+	if res.Error != nil || res.kind == rkStream {
+		i.delete(ctx, cacheKey)
+		return res.Write(ec, opts)
+	}
+	recorder := &idempotencyRecorder{ResponseWriter: ec.Response().Writer}
+	ec.Response().Writer = recorder
+	err = res.Write(ec, opts)
+	ec.Response().Writer = recorder.ResponseWriter
+	if err != nil {
+		i.delete(ctx, cacheKey)
+		return err
+	}
+	record := IdempotencyRecord{
+		Fingerprint: fingerprint,
+		HttpStatus:  ec.Response().Status,
+		ContentType: ec.Response().Header().Get(echo.HeaderContentType),
+		Body:        recorder.body.Bytes(),
+	}
+	if err := i.Cache.Put(ctx, cacheKey, record, &sdcache.PutOptions{TTL: i.TTL}); err != nil {
+		sdslog.WithError(err).Error("put idempotency record error")
+	}
+	return nil
+}
+
+func (i *idempotency) get(ctx context.Context, cacheKey string) (*IdempotencyRecord, error) {
+	v, err := i.Cache.Get(ctx, cacheKey)
+	if err != nil {
+		if sderr.Is(err, sdcache.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, sderr.Wrap(err, "get idempotency record error")
+	}
+	switch record := v.(type) {
+	case IdempotencyRecord:
+		return &record, nil
+	case *IdempotencyRecord:
+		return record, nil
+	default:
+		return nil, sderr.New("illegal idempotency record")
+	}
+}
+
+// putPending 写入处理中的标记，Cache支持sdcache.Adder时只有key不存在才写入
+func (i *idempotency) putPending(ctx context.Context, cacheKey string, pending IdempotencyRecord) (bool, error) {
+	opts := &sdcache.PutOptions{TTL: i.PendingTTL}
+	if adder, ok := i.Cache.(sdcache.Adder); ok {
+		added, err := adder.Add(ctx, cacheKey, pending, opts)
+		if err != nil {
+			return false, sderr.Wrap(err, "add idempotency record error")
+		}
+		return added, nil
+	}
+	if err := i.Cache.Put(ctx, cacheKey, pending, opts); err != nil {
+		return false, sderr.Wrap(err, "put idempotency record error")
+	}
+	return true, nil
+}
+
+func (i *idempotency) delete(ctx context.Context, cacheKey string) {
+	if err := i.Cache.Delete(ctx, cacheKey); err != nil {
+		sdslog.WithError(err).Error("delete idempotency record error")
+	}
+}
+
+// lock 进程内按key加锁，使检查和写入处理中标记之间没有同一进程的并发请求，多个进程之间依赖putPending
+func (i *idempotency) lock(cacheKey string, action func() error) error {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(cacheKey))
+	mtx := &i.locks[h.Sum32()%numIdempotencyLocks]
+	mtx.Lock()
+	defer mtx.Unlock()
+	return action()
+}
+
+// idempotencyFingerprint 计算method、path、query和请求体的摘要
+func idempotencyFingerprint(ec echo.Context) (string, error) {
+	body, err := peekRequestBody(ec)
+	if err != nil {
+		return "", err
+	}
+	req := ec.Request()
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "?" + req.URL.Query().Encode() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *idempotencyRecorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -1,14 +1,35 @@
 package sdecho
 
 import (
+	"context"
+	"github.com/gaorx/stardust5/sdconcur"
+	"github.com/gaorx/stardust5/sderr"
 	"github.com/gaorx/stardust5/sdslices"
+	"github.com/gaorx/stardust5/sdslog"
 	"github.com/labstack/echo/v4"
+	"sync"
+	"time"
 )
 
 type Menus []*Menu
 
+// MenuBadges 菜单项Id到MenuBadgeFunc的映射
+type MenuBadges map[string]MenuBadgeFunc
+
+// MenuLoader 从MenuProvider加载菜单，ReloadInterval大于0时定期重新加载，也可以调用Reload立即重新加载
+// 重新加载失败时保留之前的菜单
+type MenuLoader struct {
+	Provider       MenuProvider
+	ReloadInterval time.Duration
+	mtx            sync.RWMutex
+	menus          Menus
+	stop           chan struct{}
+	stopOnce       sync.Once
+}
+
 const (
-	keyMenus = "sdecho.menus"
+	keyMenus      = "sdecho.menus"
+	keyMenuBadges = "sdecho.menu_badges"
 )
 
 func (menus Menus) Apply(app *echo.Echo) error {
@@ -21,3 +42,77 @@ func (menus Menus) Apply(app *echo.Echo) error {
 	app.Use(middleware)
 	return nil
 }
+
+func (badges MenuBadges) Apply(app *echo.Echo) error {
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			ec.Set(keyMenuBadges, badges)
+			return next(ec)
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+func (loader *MenuLoader) Apply(app *echo.Echo) error {
+	if loader.Provider == nil {
+		return sderr.New("no menu provider")
+	}
+	if err := loader.Reload(context.Background()); err != nil {
+		return sderr.WithStack(err)
+	}
+	if loader.ReloadInterval > 0 {
+		loader.stop = make(chan struct{})
+		go loader.reloadLoop()
+	}
+
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			ec.Set(keyMenus, loader.Menus())
+			return next(ec)
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+func (loader *MenuLoader) Menus() Menus {
+	var menus Menus
+	sdconcur.LockR(&loader.mtx, func() {
+		menus = loader.menus
+	})
+	return sdslices.Ensure(menus)
+}
+
+func (loader *MenuLoader) Reload(ctx context.Context) error {
+	menus, err := loader.Provider.LoadMenus(ctx)
+	if err != nil {
+		return sderr.Wrap(err, "load menus error")
+	}
+	sdconcur.LockW(&loader.mtx, func() {
+		loader.menus = menus
+	})
+	return nil
+}
+
+// Stop 停止定期重新加载
+func (loader *MenuLoader) Stop() {
+	if loader.stop != nil {
+		loader.stopOnce.Do(func() { close(loader.stop) })
+	}
+}
+
+func (loader *MenuLoader) reloadLoop() {
+	ticker := time.NewTicker(loader.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-loader.stop:
+			return
+		case <-ticker.C:
+			if err := loader.Reload(context.Background()); err != nil {
+				sdslog.WithError(err).Error("reload menus error")
+			}
+		}
+	}
+}
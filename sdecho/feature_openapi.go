@@ -0,0 +1,65 @@
+package sdecho
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdjson"
+	"github.com/labstack/echo/v4"
+	"html"
+	"net/http"
+)
+
+type OpenAPI struct {
+	Routes    Routes
+	Info      OpenAPIInfo
+	Path      string // 默认为/openapi.json
+	SwaggerUI string // swagger ui页面的路径，为空则不提供
+}
+
+const (
+	defaultOpenAPIPath = "/openapi.json"
+)
+
+func (d OpenAPI) Apply(app *echo.Echo) error {
+	doc, err := d.Routes.OpenAPI(d.Info)
+	if err != nil {
+		return sderr.Wrap(err, "generate openapi error")
+	}
+	docJson, err := sdjson.Marshal(doc)
+	if err != nil {
+		return sderr.Wrap(err, "marshal openapi error")
+	}
+	docPath := d.Path
+	if docPath == "" {
+		docPath = defaultOpenAPIPath
+	}
+	app.GET(docPath, func(ec echo.Context) error {
+		return ec.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, docJson)
+	})
+	if d.SwaggerUI != "" {
+		page := []byte(fmt.Sprintf(swaggerUIPage, html.EscapeString(d.Info.Title), html.EscapeString(docPath)))
+		app.GET(d.SwaggerUI, func(ec echo.Context) error {
+			return ec.HTMLBlob(http.StatusOK, page)
+		})
+	}
+	return nil
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8"/>
+  <title>%s</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css"/>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>
+  window.onload = function () {
+    window.ui = SwaggerUIBundle({url: "%s", dom_id: "#swagger-ui"});
+  };
+</script>
+</body>
+</html>
+`
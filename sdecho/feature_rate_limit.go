@@ -0,0 +1,155 @@
+package sdecho
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/labstack/echo/v4"
+	"path"
+	"strconv"
+)
+
+const (
+	RateLimitByUID    = "uid"
+	RateLimitByIP     = "ip"
+	RateLimitByObject = "object"
+	RateLimitByTag    = "tag"
+)
+
+// RateLimiting 限流
+// By为ip的规则使用IPExtractor获取客户端IP，为空时使用echo.Echo.IPExtractor，两者都为空时使用echo.ExtractIPDirect()，
+// 即直接使用连接的地址，不信任客户端发送的X-Forwarded-For和X-Real-IP；部署在代理之后时需要设置只信任代理地址的IPExtractor，
+// 例如echo.ExtractIPFromXFFHeader(echo.TrustIPRange(...))
+type RateLimiting struct {
+	Limiter     RateLimiter
+	Rules       []RateLimitRule
+	IPExtractor echo.IPExtractor
+}
+
+// RateLimitRule 限流规则
+// By为uid时，未登录的请求不受此规则限制；By为object时同一个object的所有请求共享限额；By为tag时object的每个tag分别计算限额
+// ObjectId和Tag用于过滤此规则作用的object，ObjectId支持通配符
+type RateLimitRule struct {
+	By       string
+	Limit    RateLimit
+	ObjectId string
+	Tag      string
+}
+
+const (
+	keyRateLimitChecker = "sdecho.rate_limit_checker"
+)
+
+type rateLimitChecker func(context.Context, echo.Context, Token, Object) error
+
+func (rl RateLimiting) Apply(app *echo.Echo) error {
+	if rl.Limiter == nil {
+		return sderr.New("no rate limiter")
+	}
+	for _, rule := range rl.Rules {
+		switch rule.By {
+		case RateLimitByUID, RateLimitByIP, RateLimitByObject, RateLimitByTag:
+		default:
+			return sderr.NewWith("illegal rate limit rule", rule.By)
+		}
+	}
+
+	// 任何一个key被拒绝时，退回之前的key已经消耗的限额
+	checker := func(ctx context.Context, ec echo.Context, token Token, object Object) error {
+		type consumed struct {
+			key   string
+			id    string
+			limit RateLimit
+		}
+		var consumedList []consumed
+		refund := func() {
+			for _, c := range consumedList {
+				if err := rl.Limiter.Refund(ctx, c.key, c.id, c.limit); err != nil {
+					sdslog.WithError(err).Error("refund rate limit error")
+				}
+			}
+		}
+		for i, rule := range rl.Rules {
+			if !rule.match(object) {
+				continue
+			}
+			for _, k := range rule.keys(ec, token, object, rl.extractIP) {
+				key := strconv.Itoa(i) + "." + k
+				allowed, id, err := rl.Limiter.Allow(ctx, key, rule.Limit)
+				if err != nil {
+					refund()
+					return sderr.WithStack(err)
+				}
+				if !allowed {
+					refund()
+					return sderr.WithStack(ErrTooManyRequests)
+				}
+				consumedList = append(consumedList, consumed{key: key, id: id, limit: rule.Limit})
+			}
+		}
+		return nil
+	}
+
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			ec.Set(keyRateLimitChecker, rateLimitChecker(checker))
+			return next(ec)
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+func (rule RateLimitRule) match(object Object) bool {
+	if rule.ObjectId != "" {
+		if ok, err := path.Match(rule.ObjectId, object.Id()); err != nil || !ok {
+			return false
+		}
+	}
+	if rule.Tag != "" && !object.MatchTag(rule.Tag) {
+		return false
+	}
+	return true
+}
+
+func (rl *RateLimiting) extractIP(ec echo.Context) string {
+	extractor := rl.IPExtractor
+	if extractor == nil {
+		extractor = ec.Echo().IPExtractor
+	}
+	if extractor == nil {
+		extractor = echo.ExtractIPDirect()
+	}
+	return extractor(ec.Request())
+}
+
+func (rule RateLimitRule) keys(ec echo.Context, token Token, object Object, extractIP func(echo.Context) string) []string {
+	switch rule.By {
+	case RateLimitByUID:
+		if token.UID == "" {
+			return nil
+		}
+		return []string{"uid." + token.UID}
+	case RateLimitByIP:
+		return []string{"ip." + extractIP(ec)}
+	case RateLimitByObject:
+		return []string{"object." + object.Id()}
+	case RateLimitByTag:
+		var keys []string
+		for _, tag := range object.Tags() {
+			keys = append(keys, "tag."+tag)
+		}
+		return keys
+	default:
+		return nil
+	}
+}
+
+// RateLimitCheck 如果没有安装RateLimiting，则不做限制
+func RateLimitCheck(ctx context.Context, ec echo.Context, token Token, object Object) error {
+	checker, ok := Get[rateLimitChecker](ec, keyRateLimitChecker)
+	if !ok {
+		return nil
+	}
+	return checker(ctx, ec, token, expandObject(ec, object))
+}
@@ -0,0 +1,206 @@
+package sdecho
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gaorx/stardust5/sdcache"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ResponseCache 缓存Cached的endpoint的成功结果，并使用ETag和Last-Modified响应条件请求
+// 缓存的key由method、path、query(不含_token)、VaryHeaders、token中的UID、租户、locale以及请求体组成
+// Cache中的值类型为CachedResponse，使用sdcacheredis时可以使用sdcache.JsonEncoder[CachedResponse]
+type ResponseCache struct {
+	Cache       sdcache.Cache
+	TTL         time.Duration // 默认1分钟
+	VaryHeaders []string      // 参与计算key的请求头，默认为Accept-Language
+	KeyPrefix   string
+}
+
+// CachedResponse 保存在Cache中的响应
+type CachedResponse struct {
+	HttpStatus   int       `json:"http_status"`
+	ContentType  string    `json:"content_type"`
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+const (
+	keyResponseCache = "sdecho.response_cache"
+)
+
+type responseCache struct {
+	ResponseCache
+}
+
+func (rc ResponseCache) Apply(app *echo.Echo) error {
+	if rc.Cache == nil {
+		return sderr.New("no response cache")
+	}
+	if rc.TTL <= 0 {
+		rc.TTL = time.Minute
+	}
+	if rc.VaryHeaders == nil {
+		rc.VaryHeaders = []string{"Accept-Language"}
+	}
+	if rc.KeyPrefix == "" {
+		rc.KeyPrefix = "sdecho.response_cache."
+	}
+	c := &responseCache{ResponseCache: rc}
+
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			ec.Set(keyResponseCache, c)
+			return next(ec)
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+func (c *responseCache) serve(ec echo.Context, token Token, opts *ResultOptions, call func() *Result) error {
+	ctx := context.Background()
+	cacheKey, err := c.keyOf(ec, token)
+	if err != nil {
+		return ResultErr(err).Write(ec, opts)
+	}
+	if cached, err := c.get(ctx, cacheKey); err != nil {
+		sdslog.WithError(err).Error("get cached response error")
+	} else if cached != nil {
+		return writeCachedResponse(ec, cached)
+	}
+
+	res := call()
+	if res == nil {
+		res = ResultOk(nil)
+	}
+	if res.Error != nil || res.kind == rkStream {
+		return res.Write(ec, opts)
+	}
+
+	// 先写入缓冲区，得到完整的响应后再计算ETag
+	w := ec.Response().Writer
+	buffered := &bufferedResponseWriter{header: w.Header(), status: http.StatusOK}
+	ec.Response().Writer = buffered
+	err = res.Write(ec, opts)
+	ec.Response().Writer = w
+	if err != nil {
+		return err
+	}
+	resp := &CachedResponse{
+		HttpStatus:   buffered.status,
+		ContentType:  w.Header().Get(echo.HeaderContentType),
+		Body:         buffered.body.Bytes(),
+		ETag:         etagOf(buffered.body.Bytes()),
+		LastModified: time.Now().UTC().Truncate(time.Second),
+	}
+	if resp.HttpStatus == http.StatusOK {
+		if err := c.Cache.Put(ctx, cacheKey, *resp, &sdcache.PutOptions{TTL: c.TTL}); err != nil {
+			sdslog.WithError(err).Error("put cached response error")
+		}
+	}
+	ec.Response().Committed, ec.Response().Size = false, 0
+	return writeCachedResponse(ec, resp)
+}
+
+func (c *responseCache) get(ctx context.Context, cacheKey string) (*CachedResponse, error) {
+	v, err := c.Cache.Get(ctx, cacheKey)
+	if err != nil {
+		if sderr.Is(err, sdcache.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, sderr.Wrap(err, "get cached response error")
+	}
+	switch resp := v.(type) {
+	case CachedResponse:
+		return &resp, nil
+	case *CachedResponse:
+		return resp, nil
+	default:
+		return nil, sderr.New("illegal cached response")
+	}
+}
+
+func (c *responseCache) keyOf(ec echo.Context, token Token) (string, error) {
+	body, err := peekRequestBody(ec)
+	if err != nil {
+		return "", err
+	}
+	req := ec.Request()
+	query := req.URL.Query()
+	query.Del("_token")
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "?" + query.Encode() + "\n"))
+	for _, k := range c.VaryHeaders {
+		h.Write([]byte(k + ": " + url.QueryEscape(req.Header.Get(k)) + "\n"))
+	}
+	h.Write([]byte("uid: " + url.QueryEscape(token.UID) + "\n"))
+	h.Write([]byte("tenant: " + url.QueryEscape(TenantOf(ec)) + "\n"))
+	h.Write([]byte("locale: " + url.QueryEscape(requestLocale(ec)) + "\n"))
+	h.Write(body)
+	return c.KeyPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeCachedResponse 如果If-None-Match或者If-Modified-Since匹配，返回304
+func writeCachedResponse(ec echo.Context, resp *CachedResponse) error {
+	header := ec.Response().Header()
+	header.Set(echo.HeaderLastModified, resp.LastModified.Format(http.TimeFormat))
+	header.Set("ETag", resp.ETag)
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", "private, no-cache")
+	}
+	if notModified(ec.Request(), resp) {
+		return ec.NoContent(http.StatusNotModified)
+	}
+	return ec.Blob(resp.HttpStatus, resp.ContentType, resp.Body)
+}
+
+func notModified(req *http.Request, resp *CachedResponse) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, etag := range strings.Split(inm, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == "*" || etag == resp.ETag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := req.Header.Get(echo.HeaderIfModifiedSince); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !resp.LastModified.After(t)
+	}
+	return false
+}
+
+func etagOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// bufferedResponseWriter 将响应写入缓冲区，header仍然使用原始的header
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
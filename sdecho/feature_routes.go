@@ -71,6 +71,14 @@ func (routes Routes) Apply(app *echo.Echo) error {
 	return nil
 }
 
+// resultOptionsOf 中间件中返回Result时使用，Routes还没有执行时使用默认选项
+func resultOptionsOf(ec echo.Context) *ResultOptions {
+	if routes, ok := Get[*Routes](ec, keyRoutes); ok {
+		return routes.ResultOptions
+	}
+	return nil
+}
+
 func (routes Routes) ExpandEndpoints() ([]*Endpoint, error) {
 	var endpoints []*Endpoint
 
@@ -0,0 +1,161 @@
+package sdecho
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdsql"
+	"github.com/labstack/echo/v4"
+	"strings"
+)
+
+// Tenants 依次使用Resolvers解析租户ID，解析出的租户会
+// 1. 作为AccessControl中object的${tenant}变量
+// 2. 放入请求的Context(sdsql.WithTenant)，使用ec.Request().Context()访问数据库时由sdgorm.TenantPlugin和sdbun.TenantModel自动过滤
+// 3. 作为CasbinRbac的domain
+// 安装了Tokens时(需要在Tenants之前安装)，只有token中的租户与解析出的租户相同，或者IsMember返回true时才接受该租户，否则返回ErrForbidden，
+// 匿名、过期或无法解码的token不在这里检查，由endpointCheck和AccessControl处理
+type Tenants struct {
+	Resolvers []TenantResolver
+	Required  bool // 为true时没有解析出租户的请求返回ErrBadRequest
+	// IsMember 判断token的用户是否属于tenant，只对有效的非匿名token调用
+	IsMember func(ctx context.Context, ec echo.Context, token Token, tenant string) (bool, error)
+}
+
+// TenantResolver 返回空字符串表示没有解析出租户
+type TenantResolver func(ec echo.Context) (string, error)
+
+const (
+	keyTenants = "sdecho.tenants"
+	keyTenant  = "sdecho.tenant"
+)
+
+func (tenants Tenants) Apply(app *echo.Echo) error {
+	if len(tenants.Resolvers) <= 0 {
+		return sderr.New("no tenant resolvers")
+	}
+
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			ec.Set(keyTenants, &tenants)
+			tenant, err := tenants.resolve(ec)
+			if err != nil {
+				return ResultErr(err).Write(ec, resultOptionsOf(ec))
+			}
+			if tenant != "" {
+				if err := tenants.authorize(ec, tenant); err != nil {
+					return ResultErr(err).Write(ec, resultOptionsOf(ec))
+				}
+				ec.Set(keyTenant, tenant)
+				req := ec.Request()
+				ec.SetRequest(req.WithContext(sdsql.WithTenant(req.Context(), tenant)))
+			}
+			return next(ec)
+		}
+	}
+	app.Use(middleware)
+	return nil
+}
+
+func (tenants *Tenants) resolve(ec echo.Context) (string, error) {
+	for _, resolver := range tenants.Resolvers {
+		if resolver == nil {
+			continue
+		}
+		tenant, err := resolver(ec)
+		if err != nil {
+			return "", sderr.Wrap(err, "resolve tenant error")
+		}
+		if tenant != "" {
+			return tenant, nil
+		}
+	}
+	return "", nil
+}
+
+// authorize 安装了Tokens时检查token是否可以访问tenant
+func (tenants *Tenants) authorize(ec echo.Context, tenant string) error {
+	if _, ok := Get[*Tokens](ec, keyTokens); !ok {
+		return nil
+	}
+	ctx := ec.Request().Context()
+	token, err := TokenDecode(ctx, ec)
+	if err != nil || token.UID == "" {
+		return nil
+	}
+	if token.Tenant == tenant {
+		return nil
+	}
+	if tenants.IsMember != nil {
+		ok, err := tenants.IsMember(ctx, ec, token, tenant)
+		if err != nil {
+			return sderr.Wrap(err, "check tenant member error")
+		}
+		if ok {
+			return nil
+		}
+	}
+	return sderr.WithStack(ErrForbidden)
+}
+
+// TenantFromHost 从子域名中解析租户，例如suffix为.example.com时，acme.example.com的租户为acme
+func TenantFromHost(suffix string) TenantResolver {
+	return func(ec echo.Context) (string, error) {
+		host := ec.Request().Host
+		if i := strings.LastIndex(host, ":"); i >= 0 && !strings.Contains(host[i:], "]") {
+			host = host[:i]
+		}
+		if !strings.HasSuffix(host, suffix) {
+			return "", nil
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		if sub == "" || strings.Contains(sub, ".") {
+			return "", nil
+		}
+		return sub, nil
+	}
+}
+
+func TenantFromHeader(name string) TenantResolver {
+	return func(ec echo.Context) (string, error) {
+		return strings.TrimSpace(ec.Request().Header.Get(name)), nil
+	}
+}
+
+// TenantFromToken 使用token中的Tenant，token无效时不解析
+func TenantFromToken() TenantResolver {
+	return func(ec echo.Context) (string, error) {
+		if _, ok := Get[*Tokens](ec, keyTokens); !ok {
+			return "", sderr.New("no tokens for tenant resolver")
+		}
+		return TokenGet(context.Background(), ec).Tenant, nil
+	}
+}
+
+// TenantOf 返回解析出的租户，没有安装Tenants或者没有解析出租户时返回空字符串
+func TenantOf(ec echo.Context) string {
+	tenant, _ := Get[string](ec, keyTenant)
+	return tenant
+}
+
+func (c Context) Tenant() string {
+	return TenantOf(c)
+}
+
+// tenantCheck 在endpointCheck中执行，租户与token的匹配已经在中间件中检查
+func tenantCheck(ec echo.Context, _ Token) error {
+	tenants, ok := Get[*Tenants](ec, keyTenants)
+	if !ok {
+		return nil
+	}
+	if tenants.Required && TenantOf(ec) == "" {
+		return sderr.Wrap(ErrBadRequest, "no tenant")
+	}
+	return nil
+}
+
+func tenantObjectVars(ec echo.Context) map[string]string {
+	if tenant := TenantOf(ec); tenant != "" {
+		return map[string]string{"tenant": tenant}
+	}
+	return nil
+}
@@ -4,14 +4,38 @@ import (
 	"context"
 	"github.com/gaorx/stardust5/sderr"
 	"github.com/gaorx/stardust5/sdjwt"
+	"github.com/gaorx/stardust5/sdreflect"
+	"github.com/gaorx/stardust5/sdtime"
+	"github.com/gaorx/stardust5/sduuid"
 	"github.com/labstack/echo/v4"
 	"github.com/samber/lo"
+	"time"
+)
+
+const (
+	TokenKindAccess  = ""
+	TokenKindRefresh = "refresh"
 )
 
 type Token struct {
-	UID  string `json:"uid,omitempty"`
-	From string `json:"form,omitempty"`
-	At   int64  `json:"at,omitempty"`
+	UID      string `json:"uid,omitempty"`
+	From     string `json:"form,omitempty"`
+	At       int64  `json:"at,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	ExpireAt int64  `json:"expire_at,omitempty"`
+	Tenant   string `json:"tenant,omitempty"`
+}
+
+type TokenPair struct {
+	Access          string `json:"access"`
+	Refresh         string `json:"refresh,omitempty"`
+	AccessExpireAt  int64  `json:"access_expire_at,omitempty"`
+	RefreshExpireAt int64  `json:"refresh_expire_at,omitempty"`
+}
+
+func (t Token) IsExpiredAt(ms int64) bool {
+	return t.ExpireAt > 0 && ms >= t.ExpireAt
 }
 
 func tokenEncode(t Token, secret string) string {
@@ -31,10 +55,18 @@ func tokenDecode(s string, secrets []string) (Token, bool) {
 	return Token{}, false
 }
 
+func newTokenId() string {
+	return sduuid.NewV4().HexL()
+}
+
 type Tokens struct {
 	Secrets    []string
-	GetEncoded func(echo.Context) string
+	GetEncoded func(echo.Context) string // 默认为TokenFromBearer，设置了Cookie时再使用TokenFromCookie
 	IsExpired  func(echo.Context, Token) bool
+	AccessTTL  time.Duration // access token的有效期，为0则不过期
+	RefreshTTL time.Duration // refresh token的有效期，为0则不签发refresh token
+	Revoker    TokenRevoker  // 签发refresh token时必须设置，用于保证refresh token只能使用一次
+	Cookie     *TokenCookie
 }
 
 const (
@@ -46,6 +78,20 @@ func (tt Tokens) Apply(app *echo.Echo) error {
 	if len(tt.Secrets) <= 0 {
 		return sderr.New("no tokens secret")
 	}
+	if tt.RefreshTTL > 0 && tt.Revoker == nil {
+		return sderr.New("refresh token requires revoker")
+	}
+	if tt.Cookie != nil {
+		cookie := tt.Cookie.withDefaults()
+		tt.Cookie = &cookie
+	}
+	if tt.GetEncoded == nil {
+		if tt.Cookie != nil {
+			tt.GetEncoded = TokenFromAny(TokenFromBearer(), TokenFromCookie())
+		} else {
+			tt.GetEncoded = TokenFromBearer()
+		}
+	}
 
 	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(ec echo.Context) error {
@@ -65,26 +111,15 @@ func TokenGet(ctx context.Context, ec echo.Context) Token {
 	return token
 }
 
-func TokenDecode(_ context.Context, ec echo.Context) (Token, error) {
+func TokenDecode(ctx context.Context, ec echo.Context) (Token, error) {
 	if cachedToken, ok := Get[Token](ec, keyCachedToken); ok {
 		return cachedToken, nil
 	}
 	tt := MustGet[*Tokens](ec, keyTokens)
-	var encoded string
-	if tt.GetEncoded != nil {
-		encoded = tt.GetEncoded(ec)
-	} else {
-		encoded = ec.QueryParam("_token")
-	}
-	if encoded != "" {
-		t, ok := tokenDecode(encoded, tt.Secrets)
-		if !ok {
-			return Token{}, sderr.WithStack(ErrDecodeToken)
-		}
-		if tt.IsExpired != nil {
-			if tt.IsExpired(ec, t) {
-				return t, sderr.WithStack(ErrTokenExpired)
-			}
+	if encoded := tt.GetEncoded(ec); encoded != "" {
+		t, err := tt.verify(ctx, ec, encoded, TokenKindAccess)
+		if err != nil {
+			return t, err
 		}
 		ec.Set(keyCachedToken, t)
 		return t, nil
@@ -98,3 +133,157 @@ func TokenEncode(_ context.Context, ec echo.Context, t Token) string {
 	tt := MustGet[*Tokens](ec, keyTokens)
 	return tokenEncode(t, tt.Secrets[0])
 }
+
+// TokenIssue 签发token，token的租户为当前请求解析出的租户(TenantOf)，需要指定其他租户时使用TokenIssueFor
+func TokenIssue(_ context.Context, ec echo.Context, uid, from string) (TokenPair, error) {
+	if uid == "" {
+		return TokenPair{}, sderr.New("issue token without uid")
+	}
+	tt := MustGet[*Tokens](ec, keyTokens)
+	return tt.issue(Token{UID: uid, From: from, Tenant: TenantOf(ec)}), nil
+}
+
+// TokenIssueFor 使用t中的UID、From和Tenant签发token
+func TokenIssueFor(_ context.Context, ec echo.Context, t Token) (TokenPair, error) {
+	if t.UID == "" {
+		return TokenPair{}, sderr.New("issue token without uid")
+	}
+	tt := MustGet[*Tokens](ec, keyTokens)
+	return tt.issue(t), nil
+}
+
+func TokenRefresh(ctx context.Context, ec echo.Context, encodedRefresh string) (TokenPair, error) {
+	tt := MustGet[*Tokens](ec, keyTokens)
+	t, err := tt.verify(ctx, ec, encodedRefresh, TokenKindRefresh)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	// refresh token只能使用一次，并发使用同一个refresh token时只有一个能成功
+	if tt.Revoker == nil {
+		return TokenPair{}, sderr.New("no token revoker")
+	}
+	revoked, err := tt.Revoker.RevokeOnce(ctx, t)
+	if err != nil {
+		return TokenPair{}, sderr.WithStack(err)
+	}
+	if !revoked {
+		return TokenPair{}, sderr.WithStack(ErrTokenRevoked)
+	}
+	return tt.issue(t), nil
+}
+
+func TokenRevoke(ctx context.Context, ec echo.Context, t Token) error {
+	tt := MustGet[*Tokens](ec, keyTokens)
+	if tt.Revoker == nil {
+		return sderr.New("no token revoker")
+	}
+	return tt.Revoker.Revoke(ctx, t)
+}
+
+func TokenRevokeUID(ctx context.Context, ec echo.Context, uid string) error {
+	tt := MustGet[*Tokens](ec, keyTokens)
+	if tt.Revoker == nil {
+		return sderr.New("no token revoker")
+	}
+	return tt.Revoker.RevokeUID(ctx, uid)
+}
+
+func (tt *Tokens) issue(base Token) TokenPair {
+	now := sdtime.NowUnixMS()
+	expireAt := func(ttl time.Duration) int64 {
+		if ttl <= 0 {
+			return 0
+		}
+		return now + sdtime.ToMillis(ttl)
+	}
+	access := Token{
+		UID:      base.UID,
+		From:     base.From,
+		At:       now,
+		ID:       newTokenId(),
+		Kind:     TokenKindAccess,
+		ExpireAt: expireAt(tt.AccessTTL),
+		Tenant:   base.Tenant,
+	}
+	pair := TokenPair{
+		Access:         tokenEncode(access, tt.Secrets[0]),
+		AccessExpireAt: access.ExpireAt,
+	}
+	if tt.RefreshTTL > 0 {
+		refresh := Token{
+			UID:      base.UID,
+			From:     base.From,
+			At:       now,
+			ID:       newTokenId(),
+			Kind:     TokenKindRefresh,
+			ExpireAt: expireAt(tt.RefreshTTL),
+			Tenant:   base.Tenant,
+		}
+		pair.Refresh = tokenEncode(refresh, tt.Secrets[0])
+		pair.RefreshExpireAt = refresh.ExpireAt
+	}
+	return pair
+}
+
+func (tt *Tokens) verify(ctx context.Context, ec echo.Context, encoded string, kind string) (Token, error) {
+	t, ok := tokenDecode(encoded, tt.Secrets)
+	if !ok {
+		return Token{}, sderr.WithStack(ErrDecodeToken)
+	}
+	if t.Kind != kind {
+		return Token{}, sderr.WithStack(ErrDecodeToken)
+	}
+	if t.IsExpiredAt(sdtime.NowUnixMS()) {
+		return t, sderr.WithStack(ErrTokenExpired)
+	}
+	if tt.IsExpired != nil {
+		if tt.IsExpired(ec, t) {
+			return t, sderr.WithStack(ErrTokenExpired)
+		}
+	}
+	if tt.Revoker != nil {
+		revoked, err := tt.Revoker.IsRevoked(ctx, t)
+		if err != nil {
+			return t, sderr.WithStack(err)
+		}
+		if revoked {
+			return t, sderr.WithStack(ErrTokenRevoked)
+		}
+	}
+	return t, nil
+}
+
+// refresh endpoint
+
+type TokenRefreshAPI struct {
+	Path        string
+	Middlewares []echo.MiddlewareFunc
+}
+
+func (api TokenRefreshAPI) ToEndpoint() Endpoint {
+	return API{
+		Path:   api.Path,
+		Object: Public,
+		Bare:   true,
+		Func: func(ec echo.Context, req struct {
+			Refresh string `json:"refresh"` // 为空时使用cookie中的refresh token
+		}) *Result {
+			if req.Refresh != "" {
+				pair, err := TokenRefresh(context.Background(), ec, req.Refresh)
+				return ResultOf(pair, err)
+			}
+			if tt, ok := Get[*Tokens](ec, keyTokens); !ok || tt.Cookie == nil {
+				return ResultErr(sderr.Wrap(ErrBadRequest, "no refresh token"))
+			}
+			pair, err := TokenRefreshCookie(context.Background(), ec)
+			if err != nil {
+				return ResultErr(err)
+			}
+			// 使用cookie时不在响应中返回token
+			return ResultOk(TokenPair{AccessExpireAt: pair.AccessExpireAt, RefreshExpireAt: pair.RefreshExpireAt})
+		},
+		Middlewares: api.Middlewares,
+		Summary:     "refresh token",
+		DataType:    sdreflect.T[TokenPair](),
+	}.ToEndpoint()
+}
@@ -0,0 +1,44 @@
+package sdecho
+
+import (
+	"github.com/gaorx/stardust5/sdtrace"
+	"github.com/labstack/echo/v4"
+)
+
+// Trace 接受或生成请求ID和W3C traceparent，放入请求的Context(sdtrace.With)
+// 之后使用ec.Request().Context()的sdslog日志会自动带上request_id/trace_id/span_id，sdreq请求会转发给下游
+type Trace struct {
+	Skipper func(echo.Context) bool
+}
+
+const (
+	keyTrace = "sdecho.trace"
+)
+
+func (t Trace) Apply(app *echo.Echo) error {
+	middleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ec echo.Context) error {
+			if t.Skipper != nil && t.Skipper(ec) {
+				return next(ec)
+			}
+			req := ec.Request()
+			trace := sdtrace.Extract(req.Header.Get)
+			ec.Set(keyTrace, trace)
+			ec.SetRequest(req.WithContext(sdtrace.With(req.Context(), trace)))
+			ec.Response().Header().Set(sdtrace.HeaderRequestID, trace.RequestID)
+			return next(ec)
+		}
+	}
+	app.Pre(middleware)
+	return nil
+}
+
+// TraceOf 返回当前请求的trace，没有安装Trace时返回空
+func TraceOf(ec echo.Context) sdtrace.Trace {
+	trace, _ := Get[sdtrace.Trace](ec, keyTrace)
+	return trace
+}
+
+func (c Context) RequestID() string {
+	return TraceOf(c).RequestID
+}
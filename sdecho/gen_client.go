@@ -0,0 +1,143 @@
+package sdecho
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdurl"
+	"github.com/iancoleman/strcase"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+type ClientGenerator interface {
+	GenerateTo(buffs *sdcodegen.Buffers, routes Routes) error
+}
+
+func (routes Routes) GenerateTo(buffs *sdcodegen.Buffers, generators ...ClientGenerator) error {
+	for _, g := range generators {
+		if g != nil {
+			if err := g.GenerateTo(buffs, routes); err != nil {
+				return sderr.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (routes Routes) Generate(generators ...ClientGenerator) (*sdcodegen.Buffers, error) {
+	buffs := sdcodegen.NewBuffers()
+	if err := routes.GenerateTo(buffs, generators...); err != nil {
+		return nil, sderr.WithStack(err)
+	}
+	return buffs, nil
+}
+
+// clientOperation 客户端中的一个调用，只有返回*Result的endpoint会生成调用
+type clientOperation struct {
+	name       string
+	method     string
+	path       string
+	pathParams []string
+	summary    string
+	reqType    reflect.Type
+	dataType   reflect.Type
+	fieldTypes map[string]reflect.Type
+}
+
+func (op *clientOperation) fieldNames() []string {
+	var names []string
+	for name := range op.fieldTypes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (routes Routes) clientOperations() ([]*clientOperation, error) {
+	endpoints, err := routes.ExpandEndpoints()
+	if err != nil {
+		return nil, sderr.WithStack(err)
+	}
+	var ops []*clientOperation
+	names := map[string]bool{}
+	for _, endpoint := range endpoints {
+		if endpoint.page || !isResultFunc(endpoint.Func) {
+			continue
+		}
+		if endpoint.Path == "" {
+			return nil, sderr.New("no path in endpoint")
+		}
+		p := endpoint.Path
+		if routes.BasePath != "" {
+			p = sdurl.JoinPath(routes.BasePath, p)
+		}
+		method := http.MethodPost
+		if len(endpoint.Methods) > 0 && !slices.Contains(endpoint.Methods, http.MethodPost) &&
+			!slices.Contains(endpoint.Methods, "*") && !slices.Contains(endpoint.Methods, "ANY") {
+			method = endpoint.Methods[0]
+		}
+		_, pathParams := openapiPath(p)
+		name := strcase.ToLowerCamel(strings.Trim(pattNonWord.ReplaceAllString(endpoint.Path, "_"), "_"))
+		if name == "" {
+			name = "root"
+		}
+		for i, base := 2, name; names[name]; i++ {
+			name = fmt.Sprintf("%s%d", base, i)
+		}
+		names[name] = true
+		ops = append(ops, &clientOperation{
+			name:       name,
+			method:     method,
+			path:       p,
+			pathParams: pathParams,
+			summary:    endpoint.Summary,
+			reqType:    endpoint.RequestType(),
+			dataType:   endpoint.DataType,
+			fieldTypes: endpoint.fieldTypes,
+		})
+	}
+	return ops, nil
+}
+
+// clientTypes 为生成的代码中的结构体分配名称，并按照首次出现的顺序记录
+type clientTypes struct {
+	names map[reflect.Type]string
+	used  map[string]bool
+	order []reflect.Type
+}
+
+func newClientTypes() *clientTypes {
+	return &clientTypes{
+		names: map[reflect.Type]string{},
+		used:  map[string]bool{},
+	}
+}
+
+// nameOf 返回名称以及是否是新分配的名称
+func (types *clientTypes) nameOf(typ reflect.Type) (string, bool) {
+	if name, ok := types.names[typ]; ok {
+		return name, false
+	}
+	name := types.unique(strcase.ToCamel(openapiSchemaName(typ)))
+	types.names[typ] = name
+	types.order = append(types.order, typ)
+	return name, true
+}
+
+func (types *clientTypes) unique(base string) string {
+	name := base
+	for i := 2; types.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	types.used[name] = true
+	return name
+}
+
+func (types *clientTypes) reserve(names ...string) {
+	for _, name := range names {
+		types.used[name] = true
+	}
+}
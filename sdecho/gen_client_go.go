@@ -0,0 +1,277 @@
+package sdecho
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sdcodegen/sdgengo"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdreflect"
+	"github.com/iancoleman/strcase"
+	"github.com/samber/lo"
+	"reflect"
+	"strings"
+)
+
+// GoClient 生成基于sdreq的Go客户端，请求和响应结构体会在生成的包中重新定义
+type GoClient struct {
+	File    string
+	Package string // 默认使用文件所在目录名
+}
+
+var _ ClientGenerator = GoClient{}
+
+func (g GoClient) GenerateTo(buffs *sdcodegen.Buffers, routes Routes) error {
+	if g.File == "" {
+		return sderr.New("no filename on generate go client")
+	}
+	ops, err := routes.clientOperations()
+	if err != nil {
+		return sderr.WithStack(err)
+	}
+
+	types := newClientTypes()
+	types.reserve("Client", "Response", "ResponseError")
+	imports := map[string]bool{}
+
+	// 先生成调用，收集用到的类型
+	calls := sdcodegen.NewBuffer(&sdcodegen.BufferOptions{Indent: "\t"})
+	var responseTypes []string
+	for _, op := range ops {
+		name := strcase.ToCamel(op.name)
+		respType := fmt.Sprintf("Response[%s]", goTypeOf(op.dataType, types, imports))
+		if len(op.fieldTypes) > 0 {
+			respName := types.unique(name + "Response")
+			var b strings.Builder
+			b.WriteString(fmt.Sprintf("type %s struct {\n", respName))
+			b.WriteString("\t" + respType + "\n")
+			for _, fieldName := range op.fieldNames() {
+				b.WriteString("\t" + goFieldString(sdgengo.Field{
+					Name: strcase.ToCamel(fieldName),
+					Type: goTypeOf(op.fieldTypes[fieldName], types, imports),
+					Tags: []sdgengo.FieldTag{{K: "json", V: fieldName}},
+				}) + "\n")
+			}
+			b.WriteString("}\n")
+			responseTypes = append(responseTypes, b.String())
+			respType = respName
+		}
+
+		params := []sdgengo.NamedType{{Name: "ctx", Type: "context.Context"}}
+		for _, param := range op.pathParams {
+			params = append(params, sdgengo.NamedType{Name: strcase.ToLowerCamel(param), Type: "string"})
+		}
+		bodyArg := "nil"
+		if op.reqType != nil {
+			params = append(params, sdgengo.NamedType{Name: "body", Type: goTypeOf(op.reqType, types, imports)})
+			bodyArg = "body"
+		}
+		path := fmt.Sprintf("%q", op.path)
+		if len(op.pathParams) > 0 {
+			path = `"` + pattEchoPathParam.ReplaceAllStringFunc(op.path, func(s string) string {
+				return `" + url.PathEscape(` + strcase.ToLowerCamel(strings.TrimPrefix(s, ":")) + `) + "`
+			}) + `"`
+			path = strings.TrimSuffix(strings.TrimPrefix(path, `"" + `), ` + ""`)
+			imports["net/url"] = true
+		}
+		calls.NL()
+		if op.summary != "" {
+			calls.FL("// %s %s", name, op.summary)
+		}
+		sdgengo.Method(calls, name, sdgengo.NamedType{Name: "c", Type: "*Client"}, params, sdgengo.Return("*"+respType, "error"), func(w sdcodegen.Writer) {
+			w.I(1).FL("return call[%s](ctx, c, %q, %s, %s)", respType, op.method, path, bodyArg)
+		})
+	}
+
+	w := buffs.Open(g.File)
+	sdgengo.Header(w, w.Filename(), g.Package, []string{
+		"context",
+		"encoding/json",
+		"net/http",
+		"github.com/gaorx/stardust5/sderr",
+		"github.com/gaorx/stardust5/sdreq",
+		"github.com/imroc/req/v3",
+	}).NL()
+
+	// types
+	for i := 0; i < len(types.order); i++ {
+		typ := types.order[i]
+		w.FL("type %s struct {", types.names[typ])
+		for _, f := range goStructFields(typ, types, imports) {
+			w.I(1).L(goFieldString(f))
+		}
+		w.L("}").NL()
+	}
+	var importPkgs []string
+	for pkg := range imports {
+		importPkgs = append(importPkgs, pkg)
+	}
+	sdgengo.AddImportPackages(w, importPkgs)
+
+	// runtime
+	w.P(goClientRuntime)
+	for _, respType := range responseTypes {
+		w.NL().P(respType)
+	}
+	w.P(calls.String())
+	return nil
+}
+
+const goClientRuntime = `type Response[T any] struct {
+	Code  any    ` + "`json:\"code\"`" + `
+	Data  T      ` + "`json:\"data\"`" + `
+	Error string ` + "`json:\"error,omitempty\"`" + `
+}
+
+func (r *Response[T]) err() error {
+	if r.Error == "" {
+		return nil
+	}
+	return &ResponseError{Code: r.Code, Message: r.Error}
+}
+
+type ResponseError struct {
+	Code    any
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+type Client struct {
+	HTTP  *req.Client
+	Token string
+}
+
+func NewClient(baseUrl string) *Client {
+	return &Client{HTTP: sdreq.New(&sdreq.Options{BaseUrl: baseUrl})}
+}
+
+func call[R any](ctx context.Context, c *Client, method, path string, body any) (*R, error) {
+	var opts []sdreq.RequestOption
+	if c.Token != "" {
+		opts = append(opts, sdreq.Header("Authorization", "Bearer "+c.Token))
+	}
+	var resp *req.Response
+	var err error
+	switch method {
+	case http.MethodGet:
+		if body != nil {
+			params, err := toQueryParams(body)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, sdreq.QueryParams(params))
+		}
+		resp, err = sdreq.GetForResponse(ctx, c.HTTP, path, opts...)
+	case http.MethodPost:
+		resp, err = sdreq.PostForResponse(ctx, c.HTTP, path, body, opts...)
+	default:
+		request := c.HTTP.R().SetContext(ctx).SetBody(body)
+		for _, opt := range opts {
+			request = opt(request)
+		}
+		resp, err = request.Send(method, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := new(R)
+	if err := resp.UnmarshalJson(r); err != nil {
+		return nil, sderr.Wrap(err, "response unmarshal json error")
+	}
+	if r1, ok := any(r).(interface{ err() error }); ok {
+		if err := r1.err(); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+func toQueryParams(body any) (map[string]any, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, sderr.Wrap(err, "marshal query params error")
+	}
+	var params map[string]any
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, sderr.Wrap(err, "unmarshal query params error")
+	}
+	return params, nil
+}
+`
+
+var tJsonRawMessage = sdreflect.T[json.RawMessage]()
+
+func goTypeOf(typ reflect.Type, types *clientTypes, imports map[string]bool) string {
+	if typ == nil {
+		return "any"
+	}
+	switch typ {
+	case tTime:
+		imports["time"] = true
+		return "time.Time"
+	case tJsonObject:
+		return "map[string]any"
+	case tJsonArray:
+		return "[]any"
+	case tJsonRawMessage:
+		return "json.RawMessage"
+	case tBytes:
+		return "[]byte"
+	}
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return "*" + goTypeOf(typ.Elem(), types, imports)
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return typ.Kind().String()
+	case reflect.Slice:
+		return "[]" + goTypeOf(typ.Elem(), types, imports)
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", typ.Len(), goTypeOf(typ.Elem(), types, imports))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", goTypeOf(typ.Key(), types, imports), goTypeOf(typ.Elem(), types, imports))
+	case reflect.Struct:
+		if typ.Name() == "" {
+			fields := goStructFields(typ, types, imports)
+			if len(fields) <= 0 {
+				return "struct{}"
+			}
+			return "struct { " + strings.Join(lo.Map(fields, func(f sdgengo.Field, _ int) string { return goFieldString(f) }), "; ") + " }"
+		}
+		name, _ := types.nameOf(typ)
+		return name
+	default:
+		return "any"
+	}
+}
+
+func goStructFields(typ reflect.Type, types *clientTypes, imports map[string]bool) []sdgengo.Field {
+	var fields []sdgengo.Field
+	for _, f := range structFieldsByTag(typ, "json") {
+		tag := f.name
+		if f.omitempty {
+			tag += ",omitempty"
+		}
+		fields = append(fields, sdgengo.Field{
+			Name: f.goName,
+			Type: goTypeOf(f.typ, types, imports),
+			Tags: []sdgengo.FieldTag{{K: "json", V: tag}},
+		})
+	}
+	return fields
+}
+
+// goFieldString 与sdgengo.Field.String相同，但在类型和tag之间保留空格
+func goFieldString(f sdgengo.Field) string {
+	s := f.Name + " " + f.Type
+	if len(f.Tags) > 0 {
+		tags := lo.Map(f.Tags, func(ft sdgengo.FieldTag, _ int) string { return ft.String() })
+		s += " `" + strings.Join(tags, " ") + "`"
+	}
+	return s
+}
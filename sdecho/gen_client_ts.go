@@ -0,0 +1,225 @@
+package sdecho
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdcodegen"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/iancoleman/strcase"
+	"reflect"
+	"strings"
+)
+
+// TypeScriptClient 生成基于fetch的TypeScript客户端，包含所有请求和响应结构体的interface
+type TypeScriptClient struct {
+	File      string
+	ClassName string // 默认为Client
+}
+
+var _ ClientGenerator = TypeScriptClient{}
+
+func (g TypeScriptClient) GenerateTo(buffs *sdcodegen.Buffers, routes Routes) error {
+	if g.File == "" {
+		return sderr.New("no filename on generate typescript client")
+	}
+	if g.ClassName == "" {
+		g.ClassName = "Client"
+	}
+	ops, err := routes.clientOperations()
+	if err != nil {
+		return sderr.WithStack(err)
+	}
+
+	types := newClientTypes()
+	types.reserve(g.ClassName, "Response", "ResponseError", "ClientOptions")
+
+	// 先生成调用，收集用到的类型
+	calls := sdcodegen.NewBuffer(&sdcodegen.BufferOptions{Indent: "  "})
+	var responseTypes []string
+	for _, op := range ops {
+		respType := fmt.Sprintf("Response<%s>", tsTypeOf(op.dataType, types))
+		if len(op.fieldTypes) > 0 {
+			respName := types.unique(strcase.ToCamel(op.name) + "Response")
+			var b strings.Builder
+			b.WriteString(fmt.Sprintf("export interface %s extends %s {\n", respName, respType))
+			for _, name := range op.fieldNames() {
+				b.WriteString(fmt.Sprintf("  %s: %s;\n", tsPropName(name), tsTypeOf(op.fieldTypes[name], types)))
+			}
+			b.WriteString("}\n")
+			responseTypes = append(responseTypes, b.String())
+			respType = respName
+		}
+
+		var params []string
+		for _, name := range op.pathParams {
+			params = append(params, fmt.Sprintf("%s: string", strcase.ToLowerCamel(name)))
+		}
+		bodyArg := "undefined"
+		if op.reqType != nil {
+			params = append(params, fmt.Sprintf("body: %s", tsTypeOf(op.reqType, types)))
+			bodyArg = "body"
+		}
+		path := "'" + op.path + "'"
+		if len(op.pathParams) > 0 {
+			path = "`" + pattEchoPathParam.ReplaceAllStringFunc(op.path, func(s string) string {
+				return "${encodeURIComponent(" + strcase.ToLowerCamel(strings.TrimPrefix(s, ":")) + ")}"
+			}) + "`"
+		}
+		calls.NL()
+		if op.summary != "" {
+			calls.I(1).FL("/** %s */", op.summary)
+		}
+		calls.I(1).FL("%s(%s): Promise<%s> {", op.name, strings.Join(params, ", "), respType)
+		calls.I(2).FL("return this.call('%s', %s, %s);", op.method, path, bodyArg)
+		calls.I(1).L("}")
+	}
+
+	w := buffs.Open(g.File)
+	w.L("// AUTO GENERATED, DO NOT EDIT")
+	w.NL()
+
+	// types
+	for i := 0; i < len(types.order); i++ {
+		typ := types.order[i]
+		name := types.names[typ]
+		w.FL("export interface %s {", name)
+		for _, f := range structFieldsByTag(typ, "json") {
+			optional := ""
+			if f.omitempty || f.typ.Kind() == reflect.Pointer {
+				optional = "?"
+			}
+			w.I(1).FL("%s%s: %s;", tsPropName(f.name), optional, tsTypeOf(f.typ, types))
+		}
+		w.L("}")
+		w.NL()
+	}
+
+	// runtime
+	w.L(`export interface Response<T> {
+  code: any;
+  data?: T;
+  error?: string;
+}
+`)
+	for _, respType := range responseTypes {
+		w.L(respType)
+	}
+	w.T(tsClientRuntime, map[string]any{"ClassName": g.ClassName})
+	w.P(calls.String())
+	w.L("}")
+	return nil
+}
+
+const tsClientRuntime = `export class ResponseError extends Error {
+  code: any;
+  response: Response<any>;
+
+  constructor(response: Response<any>) {
+    super(response.error);
+    this.code = response.code;
+    this.response = response;
+  }
+}
+
+export interface ClientOptions {
+  baseURL?: string;
+  token?: () => string | null | undefined;
+  fetch?: typeof fetch;
+}
+
+export class {{.ClassName}} {
+  options: ClientOptions;
+
+  constructor(options: ClientOptions = {}) {
+    this.options = options;
+  }
+
+  async call<R extends Response<any>>(method: string, path: string, body?: any): Promise<R> {
+    const params = new URLSearchParams();
+    const headers: Record<string, string> = { 'Content-Type': 'application/json' };
+    const token = this.options.token?.();
+    if (token) {
+      headers['Authorization'] = 'Bearer ' + token;
+    }
+    let reqBody: string | undefined;
+    if (method === 'GET' || method === 'DELETE') {
+      for (const [k, v] of Object.entries(body ?? {})) {
+        if (v !== undefined && v !== null) {
+          params.set(k, typeof v === 'string' ? v : JSON.stringify(v));
+        }
+      }
+    } else {
+      reqBody = JSON.stringify(body ?? {});
+    }
+    let url = (this.options.baseURL ?? '') + path;
+    const qs = params.toString();
+    if (qs) {
+      url += (url.includes('?') ? '&' : '?') + qs;
+    }
+    const resp = await (this.options.fetch ?? fetch)(url, {
+      method,
+      headers,
+      body: reqBody,
+    });
+    const r = (await resp.json()) as R;
+    if (r.error) {
+      throw new ResponseError(r);
+    }
+    return r;
+  }
+`
+
+func tsTypeOf(typ reflect.Type, types *clientTypes) string {
+	if typ == nil {
+		return "any"
+	}
+	switch typ {
+	case tTime, tBytes:
+		return "string"
+	case tJsonObject:
+		return "Record<string, any>"
+	case tJsonArray:
+		return "any[]"
+	}
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return tsTypeOf(typ.Elem(), types)
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return tsTypeOf(typ.Elem(), types) + "[]"
+	case reflect.Map:
+		return fmt.Sprintf("Record<%s, %s>", tsTypeOf(typ.Key(), types), tsTypeOf(typ.Elem(), types))
+	case reflect.Struct:
+		if typ.Name() == "" {
+			var props []string
+			for _, f := range structFieldsByTag(typ, "json") {
+				optional := ""
+				if f.omitempty || f.typ.Kind() == reflect.Pointer {
+					optional = "?"
+				}
+				props = append(props, fmt.Sprintf("%s%s: %s", tsPropName(f.name), optional, tsTypeOf(f.typ, types)))
+			}
+			if len(props) <= 0 {
+				return "{}"
+			}
+			return "{ " + strings.Join(props, "; ") + " }"
+		}
+		name, _ := types.nameOf(typ)
+		return name
+	default:
+		return "any"
+	}
+}
+
+func tsPropName(name string) string {
+	if pattNonWord.MatchString(name) {
+		return "'" + name + "'"
+	}
+	return name
+}
@@ -1,12 +1,17 @@
 package sdecho
 
 import (
+	"bytes"
 	"fmt"
+	"github.com/gaorx/stardust5/sderr"
 	"github.com/gaorx/stardust5/sdreflect"
 	"github.com/gaorx/stardust5/sdstrings"
 	"github.com/labstack/echo/v4"
 	"github.com/samber/lo"
+	"io"
 	"reflect"
+	"slices"
+	"strings"
 )
 
 func contextExpandMapper(ec echo.Context) sdstrings.ExpandMapper {
@@ -25,6 +30,35 @@ func contextExpandMapper(ec echo.Context) sdstrings.ExpandMapper {
 	}
 }
 
+// requestLocale 安装I18n时使用协商的locale，否则依次从_lang参数和Accept-Language中获取语言
+func requestLocale(ec echo.Context) string {
+	if locale, ok := Get[string](ec, keyLocale); ok {
+		return locale
+	}
+	if lang := ec.QueryParam("_lang"); lang != "" {
+		return lang
+	}
+	acceptLang := ec.Request().Header.Get("Accept-Language")
+	lang, _, _ := strings.Cut(acceptLang, ",")
+	lang, _, _ = strings.Cut(lang, ";")
+	return strings.TrimSpace(lang)
+}
+
+// peekRequestBody 读取请求体后恢复，以便后续绑定
+func peekRequestBody(ec echo.Context) ([]byte, error) {
+	req := ec.Request()
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, sderr.Wrap(ErrBadRequest, "read request body error")
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
 func newAsPtr[T any]() T {
 	typ := sdreflect.T[T]()
 	if typ.Kind() == reflect.Pointer {
@@ -33,3 +67,51 @@ func newAsPtr[T any]() T {
 		return lo.Empty[T]()
 	}
 }
+
+type taggedField struct {
+	name      string
+	goName    string
+	typ       reflect.Type
+	required  bool
+	omitempty bool
+}
+
+func structFieldsByTag(typ reflect.Type, tagKey string) []taggedField {
+	var fields []taggedField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get(tagKey)
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			embedded := sf.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, structFieldsByTag(embedded, tagKey)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			if tagKey != "json" {
+				continue
+			}
+			name = sf.Name
+		}
+		validateRules := strings.Split(sf.Tag.Get("validate"), ",")
+		fields = append(fields, taggedField{
+			name:      name,
+			goName:    sf.Name,
+			typ:       sf.Type,
+			required:  slices.Contains(validateRules, "required"),
+			omitempty: slices.Contains(strings.Split(opts, ","), "omitempty"),
+		})
+	}
+	return fields
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gaorx/stardust5/sdtemplate"
 	"github.com/labstack/echo/v4"
 	"github.com/samber/lo"
+	"html/template"
 	"io"
 	"io/fs"
 )
@@ -13,13 +14,21 @@ type htmlRenderer struct {
 	loader *sdtemplate.HtmlLoader
 }
 
+// htmlRendererFuncs 解析时使用的占位函数，渲染时替换为绑定到当前请求的函数
+var htmlRendererFuncs = template.FuncMap{
+	"t":      func(key string, args ...any) string { return key },
+	"locale": func() string { return "" },
+}
+
 func MustHtmlRenderer(fsys fs.FS, eager bool) echo.Renderer {
 	return lo.Must(NewHtmlRenderer(fsys, eager))
 }
 
+// NewHtmlRenderer 模板中可以使用t函数翻译消息，使用locale函数获取当前请求的locale
 func NewHtmlRenderer(fsys fs.FS, eager bool) (echo.Renderer, error) {
 	loader, err := sdtemplate.NewHtmlLoader(fsys, &sdtemplate.HtmlLoaderOptions{
 		Eager: eager,
+		Funcs: htmlRendererFuncs,
 	})
 	if err != nil {
 		return nil, sderr.WithStack(err)
@@ -32,6 +41,18 @@ func (renderer htmlRenderer) Render(wr io.Writer, name string, data any, ec echo
 	if err != nil {
 		return sderr.WithStack(err)
 	}
+	t, err = t.Clone()
+	if err != nil {
+		return sderr.WrapWith(err, "clone template error", name)
+	}
+	t.Funcs(template.FuncMap{
+		"t": func(key string, args ...any) string {
+			return translate(ec, key, args...)
+		},
+		"locale": func() string {
+			return requestLocale(ec)
+		},
+	})
 	err = t.Execute(wr, data)
 	if err != nil {
 		return sderr.WrapWith(err, "execute template error", name)
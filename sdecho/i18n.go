@@ -0,0 +1,122 @@
+package sdecho
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sdconcur"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdload"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Catalog 多语言消息目录，locale会被规范化为小写并使用'-'分隔，例如zh-cn
+type Catalog struct {
+	mtx      sync.RWMutex
+	messages map[string]map[string]string
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{messages: map[string]map[string]string{}}
+}
+
+// LoadCatalog 使用sdload加载消息，根据扩展名选择json、yaml或toml格式
+// 文件的顶层是locale，第二层是消息的key，例如{"zh": {"bad request": "请求错误"}}
+func LoadCatalog(locs ...string) (*Catalog, error) {
+	c := NewCatalog()
+	for _, loc := range locs {
+		var messages map[string]map[string]string
+		var err error
+		switch strings.ToLower(path.Ext(loc)) {
+		case ".json":
+			messages, err = sdload.JSON[map[string]map[string]string](loc)
+		case ".yaml", ".yml":
+			messages, err = sdload.YAML[map[string]map[string]string](loc)
+		case ".toml":
+			messages, err = sdload.TOML[map[string]map[string]string](loc)
+		default:
+			return nil, sderr.NewWith("unknown catalog file format", loc)
+		}
+		if err != nil {
+			return nil, sderr.WithStack(err)
+		}
+		for locale, localeMessages := range messages {
+			c.Add(locale, localeMessages)
+		}
+	}
+	return c, nil
+}
+
+func (c *Catalog) Add(locale string, messages map[string]string) *Catalog {
+	locale = normalizeLocale(locale)
+	sdconcur.LockW(&c.mtx, func() {
+		existing, ok := c.messages[locale]
+		if !ok {
+			existing = map[string]string{}
+			c.messages[locale] = existing
+		}
+		for k, v := range messages {
+			existing[k] = v
+		}
+	})
+	return c
+}
+
+func (c *Catalog) Locales() []string {
+	var locales []string
+	sdconcur.LockR(&c.mtx, func() {
+		for locale := range c.messages {
+			locales = append(locales, locale)
+		}
+	})
+	slices.Sort(locales)
+	return locales
+}
+
+// Lookup 先查找locale，再查找locale的语言部分，例如zh-cn找不到时查找zh
+func (c *Catalog) Lookup(locale, key string) (string, bool) {
+	locale = normalizeLocale(locale)
+	var msg string
+	var ok bool
+	sdconcur.LockR(&c.mtx, func() {
+		if msg, ok = c.messages[locale][key]; ok {
+			return
+		}
+		if lang, _, found := strings.Cut(locale, "-"); found {
+			msg, ok = c.messages[lang][key]
+		}
+	})
+	return msg, ok
+}
+
+// Translate 找不到时返回key，args不为空时使用fmt.Sprintf格式化
+func (c *Catalog) Translate(locale, key string, args ...any) string {
+	msg, ok := c.Lookup(locale, key)
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// translateError 错误消息由多层用": "连接，逐层翻译
+func (c *Catalog) translateError(locale string, err error) string {
+	msg := err.Error()
+	if translated, ok := c.Lookup(locale, msg); ok {
+		return translated
+	}
+	parts := strings.Split(msg, ": ")
+	for i, part := range parts {
+		if translated, ok := c.Lookup(locale, part); ok {
+			parts[i] = translated
+		}
+	}
+	return strings.Join(parts, ": ")
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -3,10 +3,12 @@ package sdecho
 import (
 	"context"
 	"github.com/gaorx/stardust5/sdslices"
+	"github.com/gaorx/stardust5/sdslog"
 	"github.com/gaorx/stardust5/sdstrings"
 	"github.com/labstack/echo/v4"
 	"github.com/samber/lo"
 	"slices"
+	"strings"
 )
 
 type Menu struct {
@@ -15,15 +17,22 @@ type Menu struct {
 }
 
 type MenuItem struct {
-	Name     string      `json:"name"`
-	Path     string      `json:"path,omitempty"`
-	Href     string      `json:"href,omitempty"`
-	Icon     string      `json:"icon,omitempty"`
-	Children []*MenuItem `json:"children,omitempty"`
-	Object   Object      `json:"-"`
-	Tags     []string    `json:"-"`
+	Id       string            `json:"id,omitempty"` // 用于MenuBadges
+	Name     string            `json:"name"`
+	Names    map[string]string `json:"-"` // 各语言的名称，Reify时按请求的语言选择，没有时使用Name
+	Path     string            `json:"path,omitempty"`
+	Href     string            `json:"href,omitempty"`
+	Icon     string            `json:"icon,omitempty"`
+	Order    int               `json:"-"` // 同级菜单项按Order升序排列
+	Badge    any               `json:"badge,omitempty"`
+	Children []*MenuItem       `json:"children,omitempty"`
+	Object   Object            `json:"-"`
+	Tags     []string          `json:"-"`
 }
 
+// MenuBadgeFunc 返回菜单项的徽标，例如待处理的数量，返回nil则不显示
+type MenuBadgeFunc func(ctx context.Context, ec echo.Context, token Token) (any, error)
+
 func MenuReify(ctx context.Context, ec echo.Context, menuId string, tags []string) *Menu {
 	menus := MustGet[Menus](ec, keyMenus)
 	for _, menu := range menus {
@@ -62,16 +71,59 @@ func (item *MenuItem) Reify(ctx context.Context, ec echo.Context, token Token, t
 	}
 	mapper := contextExpandMapper(ec)
 	return &MenuItem{ // clone
-		Name:     item.Name,
+		Id:       item.Id,
+		Name:     item.localizedName(ec),
 		Path:     sdstrings.ExpandShellLike(item.Path, mapper),
 		Href:     sdstrings.ExpandShellLike(item.Href, mapper),
 		Icon:     item.Icon,
+		Order:    item.Order,
+		Badge:    item.badge(ctx, ec, token),
 		Object:   item.Object,
 		Children: sdslices.Ensure(reifiedChildren),
 		Tags:     slices.Clone(item.Tags),
 	}
 }
 
+// localizedName 先从Names中查找，再使用I18n中的Catalog翻译Name
+func (item *MenuItem) localizedName(ec echo.Context) string {
+	locale := requestLocale(ec)
+	if len(item.Names) > 0 && locale != "" {
+		if name, ok := item.Names[locale]; ok {
+			return name
+		}
+		lang, _, _ := strings.Cut(locale, "-")
+		if name, ok := item.Names[lang]; ok {
+			return name
+		}
+	}
+	if i, ok := Get[*I18n](ec, keyI18n); ok {
+		if name, ok := i.Catalog.Lookup(locale, item.Name); ok {
+			return name
+		}
+	}
+	return item.Name
+}
+
+func (item *MenuItem) badge(ctx context.Context, ec echo.Context, token Token) any {
+	if item.Badge != nil || item.Id == "" {
+		return item.Badge
+	}
+	badges, ok := Get[MenuBadges](ec, keyMenuBadges)
+	if !ok {
+		return nil
+	}
+	f := badges[item.Id]
+	if f == nil {
+		return nil
+	}
+	badge, err := f(ctx, ec, token)
+	if err != nil {
+		sdslog.WithError(err).With("menu_item", item.Id).Warn("get menu badge error")
+		return nil
+	}
+	return badge
+}
+
 func reifyMenuItems(ctx context.Context, ec echo.Context, token Token, tags []string, items []*MenuItem) []*MenuItem {
 	var filteredItems []*MenuItem
 	for _, item := range items {
@@ -81,5 +133,12 @@ func reifyMenuItems(ctx context.Context, ec echo.Context, token Token, tags []st
 			}
 		}
 	}
+	sortMenuItems(filteredItems)
 	return filteredItems
 }
+
+func sortMenuItems(items []*MenuItem) {
+	slices.SortStableFunc(items, func(a, b *MenuItem) int {
+		return a.Order - b.Order
+	})
+}
@@ -0,0 +1,205 @@
+package sdecho
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdload"
+	"gorm.io/gorm"
+	"path"
+	"strings"
+)
+
+type MenuProvider interface {
+	LoadMenus(ctx context.Context) (Menus, error)
+}
+
+// MenuFile 使用sdload加载菜单，根据扩展名选择json、yaml或toml格式
+type MenuFile string
+
+// MenuTable 从数据库表中加载菜单，表结构为MenuRecord
+type MenuTable struct {
+	DB    *gorm.DB
+	Table string // 默认为menu_items
+}
+
+// MenuRecord MenuTable中的一行，ParentId为空表示顶层菜单项；Enabled为false的菜单项及其子项不会被加载
+type MenuRecord struct {
+	Id       string            `gorm:"column:id;primaryKey"`
+	MenuId   string            `gorm:"column:menu_id;index"`
+	ParentId string            `gorm:"column:parent_id"`
+	Name     string            `gorm:"column:name"`
+	Names    map[string]string `gorm:"column:names;serializer:json"`
+	Path     string            `gorm:"column:path"`
+	Href     string            `gorm:"column:href"`
+	Icon     string            `gorm:"column:icon"`
+	Object   string            `gorm:"column:object"`
+	Tags     []string          `gorm:"column:tags;serializer:json"`
+	Order    int               `gorm:"column:sort_order"`
+	Enabled  bool              `gorm:"column:enabled"`
+}
+
+type menuDef struct {
+	Id    string         `json:"id" yaml:"id" toml:"id"`
+	Items []*menuItemDef `json:"items" yaml:"items" toml:"items"`
+}
+
+type menuTomlDoc struct {
+	Menus []*menuDef `toml:"menus"`
+}
+
+type menuItemDef struct {
+	Id       string            `json:"id" yaml:"id" toml:"id"`
+	Name     string            `json:"name" yaml:"name" toml:"name"`
+	Names    map[string]string `json:"names" yaml:"names" toml:"names"`
+	Path     string            `json:"path" yaml:"path" toml:"path"`
+	Href     string            `json:"href" yaml:"href" toml:"href"`
+	Icon     string            `json:"icon" yaml:"icon" toml:"icon"`
+	Order    int               `json:"order" yaml:"order" toml:"order"`
+	Object   string            `json:"object" yaml:"object" toml:"object"`
+	Tags     []string          `json:"tags" yaml:"tags" toml:"tags"`
+	Children []*menuItemDef    `json:"children" yaml:"children" toml:"children"`
+}
+
+var (
+	_ MenuProvider = Menus{}
+	_ MenuProvider = MenuFile("")
+	_ MenuProvider = MenuTable{}
+)
+
+func (menus Menus) LoadMenus(_ context.Context) (Menus, error) {
+	return menus, nil
+}
+
+func (f MenuFile) LoadMenus(_ context.Context) (Menus, error) {
+	loc := string(f)
+	var defs []*menuDef
+	var err error
+	switch strings.ToLower(path.Ext(loc)) {
+	case ".json":
+		defs, err = sdload.JSON[[]*menuDef](loc)
+	case ".yaml", ".yml":
+		defs, err = sdload.YAML[[]*menuDef](loc)
+	case ".toml":
+		// toml的顶层必须是表，菜单放在menus中
+		var doc menuTomlDoc
+		doc, err = sdload.TOML[menuTomlDoc](loc)
+		defs = doc.Menus
+	default:
+		return nil, sderr.NewWith("unknown menu file format", loc)
+	}
+	if err != nil {
+		return nil, sderr.WithStack(err)
+	}
+	var menus Menus
+	for _, def := range defs {
+		if def == nil {
+			continue
+		}
+		items, err := menuItemsFromDefs(def.Items)
+		if err != nil {
+			return nil, sderr.WithStack(err)
+		}
+		menus = append(menus, &Menu{Id: def.Id, Items: items})
+	}
+	return menus, nil
+}
+
+func (t MenuTable) LoadMenus(ctx context.Context) (Menus, error) {
+	if t.DB == nil {
+		return nil, sderr.New("nil menu db")
+	}
+	table := t.Table
+	if table == "" {
+		table = "menu_items"
+	}
+	var records []*MenuRecord
+	if err := t.DB.WithContext(ctx).Table(table).Find(&records).Error; err != nil {
+		return nil, sderr.Wrap(err, "query menu items error")
+	}
+	return menusFromRecords(records)
+}
+
+func menuItemsFromDefs(defs []*menuItemDef) ([]*MenuItem, error) {
+	var items []*MenuItem
+	for _, def := range defs {
+		if def == nil {
+			continue
+		}
+		object, err := ParseObject(def.Object)
+		if err != nil {
+			return nil, sderr.WithStack(err)
+		}
+		children, err := menuItemsFromDefs(def.Children)
+		if err != nil {
+			return nil, sderr.WithStack(err)
+		}
+		items = append(items, &MenuItem{
+			Id:       def.Id,
+			Name:     def.Name,
+			Names:    def.Names,
+			Path:     def.Path,
+			Href:     def.Href,
+			Icon:     def.Icon,
+			Order:    def.Order,
+			Children: children,
+			Object:   object,
+			Tags:     def.Tags,
+		})
+	}
+	sortMenuItems(items)
+	return items, nil
+}
+
+func menusFromRecords(records []*MenuRecord) (Menus, error) {
+	items := map[string]*MenuItem{}
+	for _, r := range records {
+		if r == nil || !r.Enabled {
+			continue
+		}
+		object, err := ParseObject(r.Object)
+		if err != nil {
+			return nil, sderr.WithStack(err)
+		}
+		items[r.Id] = &MenuItem{
+			Id:     r.Id,
+			Name:   r.Name,
+			Names:  r.Names,
+			Path:   r.Path,
+			Href:   r.Href,
+			Icon:   r.Icon,
+			Order:  r.Order,
+			Object: object,
+			Tags:   r.Tags,
+		}
+	}
+
+	var menus Menus
+	menusById := map[string]*Menu{}
+	for _, r := range records {
+		item, ok := items[r.Id]
+		if !ok {
+			continue
+		}
+		if r.ParentId != "" {
+			// 父菜单项被禁用时，子项也不加载
+			if parent, ok := items[r.ParentId]; ok {
+				parent.Children = append(parent.Children, item)
+			}
+			continue
+		}
+		menu, ok := menusById[r.MenuId]
+		if !ok {
+			menu = &Menu{Id: r.MenuId}
+			menusById[r.MenuId] = menu
+			menus = append(menus, menu)
+		}
+		menu.Items = append(menu.Items, item)
+	}
+	for _, item := range items {
+		sortMenuItems(item.Children)
+	}
+	for _, menu := range menus {
+		sortMenuItems(menu.Items)
+	}
+	return menus, nil
+}
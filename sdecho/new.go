@@ -119,9 +119,9 @@ func loggingRecover(logSkipper middleware.Skipper) echo.MiddlewareFunc {
 				sdslog.Int64("bytes_out", res.Size),
 			}
 			if finalErr == nil {
-				sdslog.With(logAttrs...).Infof("%d %s %s", statusCode, method, path)
+				sdslog.With(logAttrs...).InfofContext(req.Context(), "%d %s %s", statusCode, method, path)
 			} else {
-				sdslog.With(logAttrs...).WithError(fmt.Sprintf("%+v", finalErr)).Infof("%d %s %s", statusCode, method, path)
+				sdslog.With(logAttrs...).WithError(fmt.Sprintf("%+v", finalErr)).InfofContext(req.Context(), "%d %s %s", statusCode, method, path)
 			}
 			return sderr.Wrap(finalErr, "logging recover middleware error")
 		}
@@ -0,0 +1,360 @@
+package sdecho
+
+import (
+	"fmt"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdjson"
+	"github.com/gaorx/stardust5/sdreflect"
+	"github.com/gaorx/stardust5/sdurl"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+}
+
+const (
+	openapiVersion     = "3.0.3"
+	openapiTokenScheme = "token"
+)
+
+func (routes Routes) OpenAPI(info OpenAPIInfo) (sdjson.Object, error) {
+	endpoints, err := routes.ExpandEndpoints()
+	if err != nil {
+		return nil, sderr.WithStack(err)
+	}
+
+	schemas := newOpenapiSchemas()
+	paths := sdjson.Object{}
+	for _, endpoint := range endpoints {
+		if endpoint.Path == "" {
+			return nil, sderr.New("no path in endpoint")
+		}
+		p := endpoint.Path
+		if routes.BasePath != "" {
+			p = sdurl.JoinPath(routes.BasePath, p)
+		}
+		p, pathParams := openapiPath(p)
+		item, ok := paths[p].(sdjson.Object)
+		if !ok {
+			item = sdjson.Object{}
+			paths[p] = item
+		}
+		methods := endpoint.Methods
+		if slices.Contains(methods, "*") || slices.Contains(methods, "ANY") {
+			methods = []string{http.MethodGet, http.MethodPost}
+		}
+		for _, method := range methods {
+			item[strings.ToLower(method)] = openapiOperation(endpoint, method, p, pathParams, schemas)
+		}
+	}
+
+	infoObj := sdjson.Object{
+		"title":   info.Title,
+		"version": info.Version,
+	}
+	if info.Description != "" {
+		infoObj["description"] = info.Description
+	}
+	doc := sdjson.Object{
+		"openapi": openapiVersion,
+		"info":    infoObj,
+		"paths":   paths,
+		"components": sdjson.Object{
+			"schemas": schemas.components,
+			"securitySchemes": sdjson.Object{
+				openapiTokenScheme: sdjson.Object{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+		},
+	}
+	if len(info.Servers) > 0 {
+		var servers []sdjson.Object
+		for _, server := range info.Servers {
+			servers = append(servers, sdjson.Object{"url": server})
+		}
+		doc["servers"] = servers
+	}
+	return doc, nil
+}
+
+var pattEchoPathParam = regexp.MustCompile(`:(\w+)`)
+
+func openapiPath(p string) (string, []string) {
+	var params []string
+	p = pattEchoPathParam.ReplaceAllStringFunc(p, func(s string) string {
+		name := strings.TrimPrefix(s, ":")
+		params = append(params, name)
+		return "{" + name + "}"
+	})
+	return p, params
+}
+
+func openapiOperation(endpoint *Endpoint, method, p string, pathParams []string, schemas *openapiSchemas) sdjson.Object {
+	op := sdjson.Object{
+		"operationId": openapiOperationId(method, p),
+	}
+	if endpoint.Summary != "" {
+		op["summary"] = endpoint.Summary
+	}
+	if !endpoint.Object.IsEmpty() {
+		op["x-object"] = endpoint.Object.String()
+	}
+	if !endpoint.Bare && !endpoint.Object.IsPublic() {
+		op["security"] = []sdjson.Object{{openapiTokenScheme: []string{}}}
+	}
+
+	// parameters
+	var params []sdjson.Object
+	for _, name := range pathParams {
+		params = append(params, sdjson.Object{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   sdjson.Object{"type": "string"},
+		})
+	}
+
+	if endpoint.Idempotent {
+		params = append(params, sdjson.Object{
+			"name":   HeaderIdempotencyKey,
+			"in":     "header",
+			"schema": sdjson.Object{"type": "string", "maxLength": maxIdempotencyKeyLen},
+		})
+	}
+
+	// request
+	if reqTyp := endpoint.RequestType(); reqTyp != nil {
+		if reqTyp.Implements(sdreflect.T[BaseRequest]()) {
+			params = append(params, sdjson.Object{
+				"name":   "_flags",
+				"in":     "query",
+				"schema": sdjson.Object{"type": "string"},
+			})
+		}
+		switch method {
+		case http.MethodGet, http.MethodDelete, http.MethodHead:
+			params = append(params, openapiQueryParams(reqTyp, schemas)...)
+		default:
+			op["requestBody"] = sdjson.Object{
+				"required": true,
+				"content": sdjson.Object{
+					echo.MIMEApplicationJSON: sdjson.Object{"schema": schemas.of(reqTyp)},
+				},
+			}
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	// response
+	var content sdjson.Object
+	if endpoint.page {
+		content = sdjson.Object{
+			"text/html": sdjson.Object{"schema": sdjson.Object{"type": "string"}},
+		}
+	} else if isResultFunc(endpoint.Func) {
+		content = sdjson.Object{
+			echo.MIMEApplicationJSON: sdjson.Object{"schema": schemas.result(endpoint.DataType, endpoint.fieldTypes)},
+		}
+	} else if itemTyp := streamItemTypeOf(endpoint.Func); itemTyp != nil {
+		itemSchema := schemas.of(itemTyp)
+		content = sdjson.Object{
+			"text/event-stream":    sdjson.Object{"schema": itemSchema},
+			"application/x-ndjson": sdjson.Object{"schema": itemSchema},
+		}
+	}
+	resp := sdjson.Object{"description": http.StatusText(http.StatusOK)}
+	if content != nil {
+		resp["content"] = content
+	}
+	op["responses"] = sdjson.Object{"200": resp}
+	return op
+}
+
+func isResultFunc(f any) bool {
+	if f == nil {
+		return false
+	}
+	funcTyp := reflect.TypeOf(f)
+	if funcTyp.Kind() != reflect.Func {
+		return false
+	}
+	outTypes := sdreflect.OutTypes(funcTyp)
+	return len(outTypes) == 1 && outTypes[0] == sdreflect.T[*Result]()
+}
+
+func streamItemTypeOf(f any) reflect.Type {
+	if f == nil {
+		return nil
+	}
+	funcTyp := reflect.TypeOf(f)
+	if funcTyp.Kind() != reflect.Func {
+		return nil
+	}
+	outTypes := sdreflect.OutTypes(funcTyp)
+	if len(outTypes) != 1 || !isStreamType(outTypes[0]) {
+		return nil
+	}
+	return streamItemType(outTypes[0])
+}
+
+var pattNonWord = regexp.MustCompile(`\W+`)
+
+func openapiOperationId(method, p string) string {
+	id := pattNonWord.ReplaceAllString(p, "_")
+	return strings.ToLower(method) + "_" + strings.Trim(id, "_")
+}
+
+func openapiQueryParams(typ reflect.Type, schemas *openapiSchemas) []sdjson.Object {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	var params []sdjson.Object
+	for _, f := range structFieldsByTag(typ, "query") {
+		params = append(params, sdjson.Object{
+			"name":     f.name,
+			"in":       "query",
+			"required": f.required,
+			"schema":   schemas.of(f.typ),
+		})
+	}
+	return params
+}
+
+// schemas
+
+type openapiSchemas struct {
+	components sdjson.Object
+	names      map[reflect.Type]string
+}
+
+func newOpenapiSchemas() *openapiSchemas {
+	return &openapiSchemas{
+		components: sdjson.Object{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+func (schemas *openapiSchemas) result(dataTyp reflect.Type, fieldTypes map[string]reflect.Type) sdjson.Object {
+	var data sdjson.Object
+	if dataTyp != nil {
+		data = schemas.of(dataTyp)
+	} else {
+		data = sdjson.Object{}
+	}
+	props := sdjson.Object{
+		"code":  sdjson.Object{},
+		"data":  data,
+		"error": sdjson.Object{"type": "string"},
+	}
+	for k, typ := range fieldTypes {
+		props[k] = schemas.of(typ)
+	}
+	return sdjson.Object{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"code"},
+	}
+}
+
+var (
+	tTime       = sdreflect.T[time.Time]()
+	tBytes      = sdreflect.T[[]byte]()
+	tJsonObject = sdreflect.T[sdjson.Object]()
+	tJsonArray  = sdreflect.T[sdjson.Array]()
+)
+
+func (schemas *openapiSchemas) of(typ reflect.Type) sdjson.Object {
+	switch typ {
+	case tTime:
+		return sdjson.Object{"type": "string", "format": "date-time"}
+	case tBytes:
+		return sdjson.Object{"type": "string", "format": "byte"}
+	case tJsonObject:
+		return sdjson.Object{"type": "object"}
+	case tJsonArray:
+		return sdjson.Object{"type": "array", "items": sdjson.Object{}}
+	}
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return schemas.of(typ.Elem())
+	case reflect.Bool:
+		return sdjson.Object{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return sdjson.Object{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return sdjson.Object{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return sdjson.Object{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return sdjson.Object{"type": "number", "format": "double"}
+	case reflect.String:
+		return sdjson.Object{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return sdjson.Object{"type": "array", "items": schemas.of(typ.Elem())}
+	case reflect.Map:
+		return sdjson.Object{"type": "object", "additionalProperties": schemas.of(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return schemas.structOf(typ)
+		}
+		return sdjson.Object{"$ref": "#/components/schemas/" + schemas.ref(typ)}
+	default:
+		return sdjson.Object{}
+	}
+}
+
+func (schemas *openapiSchemas) ref(typ reflect.Type) string {
+	if name, ok := schemas.names[typ]; ok {
+		return name
+	}
+	name := openapiSchemaName(typ)
+	for i := 2; schemas.components.Has(name); i++ {
+		name = fmt.Sprintf("%s%d", openapiSchemaName(typ), i)
+	}
+	schemas.names[typ] = name
+	schemas.components[name] = sdjson.Object{} // 占位，避免递归类型死循环
+	schemas.components[name] = schemas.structOf(typ)
+	return name
+}
+
+func (schemas *openapiSchemas) structOf(typ reflect.Type) sdjson.Object {
+	props := sdjson.Object{}
+	var required []string
+	for _, f := range structFieldsByTag(typ, "json") {
+		props[f.name] = schemas.of(f.typ)
+		if f.required {
+			required = append(required, f.name)
+		}
+	}
+	o := sdjson.Object{"type": "object", "properties": props}
+	if len(required) > 0 {
+		o["required"] = required
+	}
+	return o
+}
+
+var pattPkgPath = regexp.MustCompile(`[\w./-]*\.`)
+
+func openapiSchemaName(typ reflect.Type) string {
+	name := pattPkgPath.ReplaceAllString(typ.Name(), "")
+	name = pattNonWord.ReplaceAllString(name, "_")
+	return strings.Trim(name, "_")
+}
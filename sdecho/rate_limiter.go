@@ -0,0 +1,286 @@
+package sdecho
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sdconcur"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdtime"
+	"github.com/gaorx/stardust5/sduuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	RateLimitTokenBucket   = "token_bucket"
+	RateLimitSlidingWindow = "sliding_window"
+)
+
+type RateLimit struct {
+	Rate   int           // 每个Period内允许的请求数
+	Period time.Duration // 默认为1秒
+	Burst  int           // 令牌桶容量，仅用于token bucket，默认为Rate
+}
+
+type RateLimiter interface {
+	// Allow 返回是否允许，允许时同时返回本次请求的标识，用于Refund
+	Allow(ctx context.Context, key string, limit RateLimit) (bool, string, error)
+	// Refund 退回一次Allow成功时消耗的限额，用于多个key中有一个被拒绝时，id为Allow返回的标识
+	Refund(ctx context.Context, key string, id string, limit RateLimit) error
+}
+
+func (limit RateLimit) trim() RateLimit {
+	if limit.Period <= 0 {
+		limit.Period = time.Second
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Rate
+	}
+	return limit
+}
+
+// memory
+
+type memoryRateLimiter struct {
+	mtx       sync.Mutex
+	algorithm string
+	buckets   map[string]*memoryTokenBucket
+	windows   map[string]*memorySlidingWindow
+	counter   int
+	seq       int64
+}
+
+type memoryTokenBucket struct {
+	tokens float64
+	last   int64
+	period int64
+}
+
+type memorySlidingWindow struct {
+	entries []memoryWindowEntry
+	period  int64
+}
+
+type memoryWindowEntry struct {
+	at int64
+	id string
+}
+
+func NewMemoryRateLimiter(algorithm string) (RateLimiter, error) {
+	if err := checkRateLimitAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	return &memoryRateLimiter{
+		algorithm: algorithm,
+		buckets:   map[string]*memoryTokenBucket{},
+		windows:   map[string]*memorySlidingWindow{},
+	}, nil
+}
+
+func (l *memoryRateLimiter) Allow(_ context.Context, key string, limit RateLimit) (bool, string, error) {
+	limit = limit.trim()
+	if limit.Rate <= 0 {
+		return false, "", nil
+	}
+	now := sdtime.NowUnixMS()
+	period := sdtime.ToMillis(limit.Period)
+	allowed, id := false, ""
+	sdconcur.Lock(&l.mtx, func() {
+		l.gc(now)
+		switch l.algorithm {
+		case RateLimitTokenBucket:
+			b, ok := l.buckets[key]
+			if !ok {
+				b = &memoryTokenBucket{tokens: float64(limit.Burst), last: now}
+				l.buckets[key] = b
+			}
+			b.period = period
+			b.tokens += float64(now-b.last) * float64(limit.Rate) / float64(period)
+			if b.tokens > float64(limit.Burst) {
+				b.tokens = float64(limit.Burst)
+			}
+			b.last = now
+			if b.tokens >= 1 {
+				b.tokens -= 1
+				allowed = true
+			}
+		case RateLimitSlidingWindow:
+			w, ok := l.windows[key]
+			if !ok {
+				w = &memorySlidingWindow{}
+				l.windows[key] = w
+			}
+			w.period = period
+			i := 0
+			for i < len(w.entries) && w.entries[i].at <= now-period {
+				i++
+			}
+			w.entries = w.entries[i:]
+			if len(w.entries) < limit.Rate {
+				l.seq++
+				id = strconv.FormatInt(l.seq, 10)
+				w.entries = append(w.entries, memoryWindowEntry{at: now, id: id})
+				allowed = true
+			}
+		}
+	})
+	return allowed, id, nil
+}
+
+func (l *memoryRateLimiter) Refund(_ context.Context, key string, id string, limit RateLimit) error {
+	limit = limit.trim()
+	sdconcur.Lock(&l.mtx, func() {
+		switch l.algorithm {
+		case RateLimitTokenBucket:
+			if b, ok := l.buckets[key]; ok {
+				b.tokens = min(b.tokens+1, float64(limit.Burst))
+			}
+		case RateLimitSlidingWindow:
+			if w, ok := l.windows[key]; ok {
+				w.entries = lo.Reject(w.entries, func(e memoryWindowEntry, _ int) bool { return e.id == id })
+			}
+		}
+	})
+	return nil
+}
+
+func (l *memoryRateLimiter) gc(now int64) {
+	l.counter++
+	if l.counter < 10000 {
+		return
+	}
+	l.counter = 0
+	for k, b := range l.buckets {
+		if now-b.last > b.period {
+			delete(l.buckets, k)
+		}
+	}
+	for k, w := range l.windows {
+		if len(w.entries) <= 0 || now-w.entries[len(w.entries)-1].at > w.period {
+			delete(l.windows, k)
+		}
+	}
+}
+
+// redis
+
+type redisRateLimiter struct {
+	client    redis.UniversalClient
+	algorithm string
+	keyPrefix string
+}
+
+var (
+	redisTokenBucketScript = redis.NewScript(`
+local tokens_per_ms = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local v = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(v[1]) or burst
+local last = tonumber(v[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * tokens_per_ms)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return allowed
+`)
+	redisSlidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local rate = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - period)
+if redis.call('ZCARD', KEYS[1]) < rate then
+  redis.call('ZADD', KEYS[1], now, ARGV[4])
+  redis.call('PEXPIRE', KEYS[1], period)
+  return 1
+end
+return 0
+`)
+	redisTokenBucketRefundScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local tokens = tonumber(redis.call('HGET', KEYS[1], 'tokens'))
+if tokens then
+  redis.call('HSET', KEYS[1], 'tokens', tostring(math.min(burst, tokens + 1)))
+end
+return 0
+`)
+)
+
+// NewRedisRateLimiter client可以由sdredis.Dial创建
+func NewRedisRateLimiter(client redis.UniversalClient, algorithm string, keyPrefix string) (RateLimiter, error) {
+	if client == nil {
+		return nil, sderr.New("nil redis client")
+	}
+	if err := checkRateLimitAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	if keyPrefix == "" {
+		keyPrefix = "sdecho.rate_limit."
+	}
+	return &redisRateLimiter{
+		client:    client,
+		algorithm: algorithm,
+		keyPrefix: keyPrefix,
+	}, nil
+}
+
+func (l *redisRateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (bool, string, error) {
+	limit = limit.trim()
+	if limit.Rate <= 0 {
+		return false, "", nil
+	}
+	now := sdtime.NowUnixMS()
+	period := sdtime.ToMillis(limit.Period)
+	var r int64
+	var member string
+	var err error
+	switch l.algorithm {
+	case RateLimitTokenBucket:
+		tokensPerMs := float64(limit.Rate) / float64(period)
+		ttl := int64(float64(limit.Burst)/tokensPerMs) + period
+		r, err = redisTokenBucketScript.Run(ctx, l.client, []string{l.keyPrefix + key}, tokensPerMs, limit.Burst, now, ttl).Int64()
+	case RateLimitSlidingWindow:
+		member = sduuid.NewV4().HexL()
+		r, err = redisSlidingWindowScript.Run(ctx, l.client, []string{l.keyPrefix + key}, now, period, limit.Rate, member).Int64()
+	}
+	if err != nil {
+		return false, "", sderr.Wrap(err, "redis rate limit error")
+	}
+	if r != 1 {
+		return false, "", nil
+	}
+	return true, member, nil
+}
+
+func (l *redisRateLimiter) Refund(ctx context.Context, key string, id string, limit RateLimit) error {
+	limit = limit.trim()
+	var err error
+	switch l.algorithm {
+	case RateLimitTokenBucket:
+		err = redisTokenBucketRefundScript.Run(ctx, l.client, []string{l.keyPrefix + key}, limit.Burst).Err()
+	case RateLimitSlidingWindow:
+		if id != "" {
+			err = l.client.ZRem(ctx, l.keyPrefix+key, id).Err()
+		}
+	}
+	if err != nil {
+		return sderr.Wrap(err, "redis rate limit refund error")
+	}
+	return nil
+}
+
+func checkRateLimitAlgorithm(algorithm string) error {
+	switch algorithm {
+	case RateLimitTokenBucket, RateLimitSlidingWindow:
+		return nil
+	default:
+		return sderr.NewWith("illegal rate limit algorithm", algorithm)
+	}
+}
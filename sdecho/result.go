@@ -7,15 +7,19 @@ import (
 	"github.com/gaorx/stardust5/sdjson"
 	"github.com/gaorx/stardust5/sdreflect"
 	"github.com/gaorx/stardust5/sdslices"
+	"github.com/gaorx/stardust5/sdvalidator"
 	"github.com/labstack/echo/v4"
+	"maps"
 	"net/http"
 	"reflect"
+	"strings"
 )
 
 const (
-	rkRaw  = "RAW"
-	rkJson = "JSON"
-	rkHtml = "HTML"
+	rkRaw    = "RAW"
+	rkJson   = "JSON"
+	rkHtml   = "HTML"
+	rkStream = "STREAM"
 )
 
 type Result struct {
@@ -39,25 +43,29 @@ type Result struct {
 }
 
 type ResultOptions struct {
-	CodeOk           any
-	CodeBadRequest   any
-	CodeTokenExpired any
-	CodeUnauthorized any
-	CodeForbidden    any
-	CodeLogin        any
-	CodeNotFound     any
-	CodeUnknown      any
+	CodeOk              any
+	CodeBadRequest      any
+	CodeTokenExpired    any
+	CodeUnauthorized    any
+	CodeForbidden       any
+	CodeLogin           any
+	CodeNotFound        any
+	CodeUnknown         any
+	CodeTooManyRequests any
+	CodeConflict        any
 }
 
 var defaultResultOptions = &ResultOptions{
-	CodeOk:           200,
-	CodeBadRequest:   400,
-	CodeTokenExpired: 701,
-	CodeUnauthorized: 401,
-	CodeForbidden:    403,
-	CodeLogin:        702,
-	CodeNotFound:     404,
-	CodeUnknown:      500,
+	CodeOk:              200,
+	CodeBadRequest:      400,
+	CodeTokenExpired:    701,
+	CodeUnauthorized:    401,
+	CodeForbidden:       403,
+	CodeLogin:           702,
+	CodeNotFound:        404,
+	CodeUnknown:         500,
+	CodeTooManyRequests: 429,
+	CodeConflict:        409,
 }
 
 func (r *Result) Write(ec echo.Context, opts *ResultOptions) error {
@@ -115,9 +123,9 @@ func (r *Result) Write(ec echo.Context, opts *ResultOptions) error {
 		if r1.Error == nil {
 			r1.Code = selectCode(opts1.CodeOk, defaultResultOptions.CodeOk)
 		} else {
-			if sderr.Is(r1.Error, ErrBadRequest) {
+			if sderr.Is(r1.Error, ErrBadRequest) || sdvalidator.FieldsErrors(r1.Error) != nil {
 				r1.Code = selectCode(opts1.CodeBadRequest, defaultResultOptions.CodeBadRequest)
-			} else if sderr.Is(r1.Error, ErrTokenExpired) || sderr.Is(r1.Error, ErrDecodeToken) {
+			} else if sderr.Is(r1.Error, ErrTokenExpired) || sderr.Is(r1.Error, ErrDecodeToken) || sderr.Is(r1.Error, ErrTokenRevoked) {
 				r1.Code = selectCode(opts1.CodeTokenExpired, defaultResultOptions.CodeTokenExpired)
 			} else if sderr.Is(r1.Error, ErrUnauthorized) {
 				r1.Code = selectCode(opts1.CodeUnauthorized, defaultResultOptions.CodeUnauthorized)
@@ -125,6 +133,10 @@ func (r *Result) Write(ec echo.Context, opts *ResultOptions) error {
 				r1.Code = selectCode(opts1.CodeForbidden, defaultResultOptions.CodeForbidden)
 			} else if sderr.Is(r1.Error, ErrLogin) {
 				r1.Code = selectCode(opts1.CodeLogin, defaultResultOptions.CodeLogin)
+			} else if sderr.Is(r1.Error, ErrTooManyRequests) {
+				r1.Code = selectCode(opts1.CodeTooManyRequests, defaultResultOptions.CodeTooManyRequests)
+			} else if sderr.Is(r1.Error, ErrConflict) {
+				r1.Code = selectCode(opts1.CodeConflict, defaultResultOptions.CodeConflict)
 			} else if sdnotfounderr.Is(r1.Error) {
 				r1.Code = selectCode(opts1.CodeNotFound, defaultResultOptions.CodeNotFound)
 			} else {
@@ -133,6 +145,33 @@ func (r *Result) Write(ec echo.Context, opts *ResultOptions) error {
 		}
 	}
 
+	if _, ok := Get[*metrics](ec, keyMetrics); ok {
+		ec.Set(keyResultCode, r1.Code)
+	}
+
+	// validation errors
+	if r1.Error != nil && (r1.kind == rkJson || r1.kind == rkHtml) {
+		if fieldErrs := sdvalidator.Translate(r1.Error, requestLocale(ec)); len(fieldErrs) > 0 {
+			msgs := make([]string, 0, len(fieldErrs))
+			for _, fieldErr := range fieldErrs {
+				msgs = append(msgs, fieldErr.Message)
+			}
+			r1.Error = sderr.New(strings.Join(msgs, "; "))
+			r1.Fields = maps.Clone(r1.Fields)
+			if r1.Fields == nil {
+				r1.Fields = map[string]any{}
+			}
+			r1.Fields["fields"] = fieldErrs
+		}
+	}
+
+	// i18n
+	if r1.Error != nil && (r1.kind == rkJson || r1.kind == rkHtml) {
+		if i, ok := Get[*I18n](ec, keyI18n); ok {
+			r1.Error = sderr.New(i.Catalog.translateError(requestLocale(ec), r1.Error))
+		}
+	}
+
 	// write
 	switch r1.kind {
 	case rkRaw:
@@ -141,6 +180,8 @@ func (r *Result) Write(ec echo.Context, opts *ResultOptions) error {
 		return r1.writeJson(ec)
 	case rkHtml:
 		return r1.writeHtml(ec)
+	case rkStream:
+		return r1.writeStream(ec)
 	default:
 		panic(sderr.NewWith("illegal result format", r1.kind))
 	}
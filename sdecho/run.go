@@ -0,0 +1,153 @@
+package sdecho
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type RunOptions struct {
+	Addr            string        // 默认为:8080
+	CertFile        string        // CertFile和KeyFile都不为空时使用TLS(同时支持HTTP/2)
+	KeyFile         string        //
+	H2C             bool          // 不使用TLS时支持HTTP/2 cleartext
+	ShutdownTimeout time.Duration // 停止时等待请求处理完成和执行OnShutdown的总时间，默认为15s
+	Signals         []os.Signal   // 收到这些信号时停止，默认为SIGINT和SIGTERM
+}
+
+type lifecycle struct {
+	mtx   sync.Mutex
+	hooks []shutdownHook
+}
+
+type shutdownHook struct {
+	name string
+	f    func(context.Context) error
+}
+
+// lifecycles 按照*echo.Echo保存lifecycle，使直接构造的Echo{Echo: e}和Feature也可以注册OnShutdown
+var lifecycles sync.Map
+
+func lifecycleOf(app *echo.Echo) *lifecycle {
+	l, _ := lifecycles.LoadOrStore(app, &lifecycle{})
+	return l.(*lifecycle)
+}
+
+// OnShutdown 注册停止时需要关闭的资源，在server停止接受请求并处理完已有请求后，按照注册的逆序执行
+func (e Echo) OnShutdown(name string, f func(context.Context) error) {
+	if f == nil {
+		return
+	}
+	lifecycleOf(e.Echo).add(name, f)
+}
+
+// Run 启动server并阻塞，直到ctx结束、收到信号或者server出错，然后优雅停止
+func (e Echo) Run(ctx context.Context, opts *RunOptions) error {
+	opts1 := lo.FromPtr(opts)
+	if opts1.Addr == "" {
+		opts1.Addr = ":8080"
+	}
+	if opts1.ShutdownTimeout <= 0 {
+		opts1.ShutdownTimeout = 15 * time.Second
+	}
+	if len(opts1.Signals) <= 0 {
+		opts1.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	useTLS := opts1.CertFile != "" && opts1.KeyFile != ""
+
+	srv := e.Server
+	srv.Addr = opts1.Addr
+	srv.Handler = e.Echo
+	if useTLS {
+		cert, err := tls.LoadX509KeyPair(opts1.CertFile, opts1.KeyFile)
+		if err != nil {
+			return sderr.WrapWith(err, "load tls cert error", opts1.CertFile)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	} else if opts1.H2C {
+		srv.Handler = h2c.NewHandler(e.Echo, &http2.Server{})
+	}
+	if srv.ErrorLog == nil {
+		srv.ErrorLog = e.StdLogger
+	}
+
+	ln, err := net.Listen("tcp", opts1.Addr)
+	if err != nil {
+		return sderr.WrapWith(err, "listen error", opts1.Addr)
+	}
+	e.Listener = ln
+
+	ctx, stop := signal.NotifyContext(ctx, opts1.Signals...)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() {
+		if useTLS {
+			serveErr <- srv.ServeTLS(ln, "", "")
+		} else {
+			serveErr <- srv.Serve(ln)
+		}
+	}()
+	sdslog.Infof("http server started on %s", ln.Addr())
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		sdslog.Infof("http server shutting down")
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			runErr = sderr.Wrap(err, "http server error")
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts1.ShutdownTimeout)
+	defer cancel()
+	var errs []error
+	if runErr != nil {
+		errs = append(errs, runErr)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, sderr.Wrap(err, "shutdown http server error"))
+	}
+	errs = append(errs, lifecycleOf(e.Echo).shutdown(shutdownCtx)...)
+	if len(errs) > 0 {
+		return sderr.Combine(errs)
+	}
+	sdslog.Infof("http server stopped")
+	return nil
+}
+
+func (l *lifecycle) add(name string, f func(context.Context) error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.hooks = append(l.hooks, shutdownHook{name: name, f: f})
+}
+
+func (l *lifecycle) shutdown(ctx context.Context) []error {
+	l.mtx.Lock()
+	hooks := l.hooks
+	l.hooks = nil
+	l.mtx.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if err := hook.f(ctx); err != nil {
+			sdslog.WithError(err).Errorf("shutdown %s error", hook.name)
+			errs = append(errs, sderr.WrapWith(err, "shutdown error", hook.name))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,274 @@
+package sdecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdreflect"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/lo"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StreamSSE    = "sse"
+	StreamNDJSON = "ndjson"
+)
+
+const (
+	defaultSSEHeartbeat = 15 * time.Second
+)
+
+// SSEEvent 在SSE流中使用此类型可以指定id、event和retry，其他类型的元素只写入data
+// ID和Event不能包含\r、\n，否则写入时返回错误并结束流
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  any
+	Retry time.Duration
+}
+
+// Stream 流式响应的数据源
+// Source可以是chan T、<-chan T或者迭代器func(yield func(T) bool)；元素如果是error，写入错误后结束
+// 客户端断开连接后停止读取，chan的生产者应当监听请求的Context以便及时退出
+type Stream struct {
+	Format    string // sse或ndjson
+	Source    any
+	Heartbeat time.Duration // SSE的心跳间隔，默认15秒，小于0则不发送心跳
+}
+
+func ResultSSE(source any) *Result {
+	return ResultStream(Stream{Format: StreamSSE, Source: source})
+}
+
+func ResultNDJSON(source any) *Result {
+	return ResultStream(Stream{Format: StreamNDJSON, Source: source})
+}
+
+func ResultStream(stream Stream) *Result {
+	return &Result{kind: rkStream, Data: stream}
+}
+
+func isStreamType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Chan:
+		return typ.ChanDir()&reflect.RecvDir != 0
+	case reflect.Func:
+		if typ.NumIn() != 1 || typ.NumOut() != 0 {
+			return false
+		}
+		yieldTyp := typ.In(0)
+		return yieldTyp.Kind() == reflect.Func &&
+			yieldTyp.NumIn() == 1 &&
+			yieldTyp.NumOut() == 1 &&
+			yieldTyp.Out(0) == sdreflect.TBool
+	default:
+		return false
+	}
+}
+
+func streamItemType(typ reflect.Type) reflect.Type {
+	switch typ.Kind() {
+	case reflect.Chan:
+		return typ.Elem()
+	case reflect.Func:
+		return typ.In(0).In(0)
+	default:
+		return nil
+	}
+}
+
+// streamFormatOf 根据Accept选择流的格式，默认为SSE
+func streamFormatOf(ec echo.Context) string {
+	accept := ec.Request().Header.Get(echo.HeaderAccept)
+	if strings.Contains(accept, "application/x-ndjson") || strings.Contains(accept, "application/jsonl") {
+		return StreamNDJSON
+	}
+	return StreamSSE
+}
+
+func (r *Result) writeStream(ec echo.Context) error {
+	stream := r.Data.(Stream)
+	if stream.Source == nil {
+		return sderr.New("nil stream source")
+	}
+	sourceVal := reflect.ValueOf(stream.Source)
+	if !isStreamType(sourceVal.Type()) {
+		return sderr.NewWith("illegal stream source", sourceVal.Type().String())
+	}
+	heartbeat := stream.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = defaultSSEHeartbeat
+	}
+	if stream.Format != StreamSSE {
+		heartbeat = 0
+	}
+
+	res := ec.Response()
+	for k, v := range r.Headers {
+		res.Header().Set(k, v)
+	}
+	if stream.Format == StreamSSE {
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Connection", "keep-alive")
+	} else {
+		res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	}
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	ctx := ec.Request().Context()
+	items, stop := streamChan(sourceVal)
+	defer stop()
+
+	var ticker *time.Ticker
+	var tickerC <-chan time.Time
+	if heartbeat > 0 {
+		ticker = time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tickerC = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tickerC:
+			if _, err := res.Write([]byte(": ping\n\n")); err != nil {
+				return nil
+			}
+			res.Flush()
+		case item, ok := <-items:
+			if !ok {
+				return nil
+			}
+			var frame []byte
+			var err error
+			if stream.Format == StreamSSE {
+				frame, err = sseFrame(item)
+			} else {
+				frame, err = ndjsonFrame(item)
+			}
+			if err != nil {
+				return sderr.WithStack(err)
+			}
+			if _, err := res.Write(frame); err != nil {
+				// 客户端已断开
+				return nil
+			}
+			res.Flush()
+			if _, isErr := item.(error); isErr {
+				return nil
+			}
+		}
+	}
+}
+
+// streamChan 将chan或迭代器统一转换为chan，调用stop后迭代器中的yield返回false
+func streamChan(sourceVal reflect.Value) (<-chan any, func()) {
+	items := make(chan any)
+	done := make(chan struct{})
+	stop := func() { close(done) }
+	send := func(item any) bool {
+		select {
+		case items <- item:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	if sourceVal.Kind() == reflect.Chan {
+		go func() {
+			defer close(items)
+			cases := []reflect.SelectCase{
+				{Dir: reflect.SelectRecv, Chan: sourceVal},
+				{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)},
+			}
+			for {
+				chosen, v, ok := reflect.Select(cases)
+				if chosen != 0 || !ok {
+					return
+				}
+				if !send(v.Interface()) {
+					return
+				}
+			}
+		}()
+	} else {
+		yieldTyp := sourceVal.Type().In(0)
+		yield := reflect.MakeFunc(yieldTyp, func(args []reflect.Value) []reflect.Value {
+			return []reflect.Value{reflect.ValueOf(send(args[0].Interface()))}
+		})
+		go func() {
+			defer close(items)
+			if ok := lo.Try0(func() {
+				sourceVal.Call([]reflect.Value{yield})
+			}); !ok {
+				sdslog.Error("call stream iterator error")
+			}
+		}()
+	}
+	return items, stop
+}
+
+func sseFrame(item any) ([]byte, error) {
+	var event SSEEvent
+	switch item1 := item.(type) {
+	case SSEEvent:
+		event = item1
+	case *SSEEvent:
+		event = *item1
+	case error:
+		event = SSEEvent{Event: "error", Data: map[string]any{"error": item1.Error()}}
+	default:
+		event = SSEEvent{Data: item}
+	}
+	// ID和Event中的换行会产生额外的字段或者事件
+	if strings.ContainsAny(event.ID, "\r\n") || strings.ContainsAny(event.Event, "\r\n") {
+		return nil, sderr.NewWith("illegal sse id or event", sderr.Attrs{"id": event.ID, "event": event.Event})
+	}
+	var buff bytes.Buffer
+	if event.ID != "" {
+		buff.WriteString("id: " + event.ID + "\n")
+	}
+	if event.Event != "" {
+		buff.WriteString("event: " + event.Event + "\n")
+	}
+	if event.Retry > 0 {
+		buff.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	var data string
+	if s, ok := event.Data.(string); ok {
+		data = s
+	} else {
+		raw, err := json.Marshal(event.Data)
+		if err != nil {
+			return nil, sderr.Wrap(err, "marshal sse data error")
+		}
+		data = string(raw)
+	}
+	// \r和\r\n在SSE中也是换行
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buff.WriteString("data: " + line + "\n")
+	}
+	buff.WriteString("\n")
+	return buff.Bytes(), nil
+}
+
+func ndjsonFrame(item any) ([]byte, error) {
+	if err, ok := item.(error); ok {
+		item = map[string]any{"error": err.Error()}
+	}
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return nil, sderr.Wrap(err, "marshal ndjson error")
+	}
+	return append(raw, '\n'), nil
+}
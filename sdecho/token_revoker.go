@@ -0,0 +1,244 @@
+package sdecho
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sdcache"
+	"github.com/gaorx/stardust5/sdconcur"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdparse"
+	"github.com/gaorx/stardust5/sdtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type TokenRevoker interface {
+	// Revoke 吊销某一个token，直到它过期
+	Revoke(ctx context.Context, t Token) error
+	// RevokeUID 吊销某个用户在此时之前签发的所有token
+	RevokeUID(ctx context.Context, uid string) error
+	// RevokeOnce 原子地检查并吊销token，如果token已经被吊销则返回false
+	RevokeOnce(ctx context.Context, t Token) (bool, error)
+	IsRevoked(ctx context.Context, t Token) (bool, error)
+}
+
+// memory
+
+type memoryTokenRevoker struct {
+	mtx     sync.Mutex
+	ids     map[string]int64
+	uids    map[string]int64
+	maxTTL  time.Duration
+	counter int
+}
+
+func NewMemoryTokenRevoker(maxTTL time.Duration) TokenRevoker {
+	return &memoryTokenRevoker{
+		ids:    map[string]int64{},
+		uids:   map[string]int64{},
+		maxTTL: maxTTL,
+	}
+}
+
+func (r *memoryTokenRevoker) Revoke(_ context.Context, t Token) error {
+	if t.ID == "" {
+		return sderr.New("revoke token without id")
+	}
+	sdconcur.Lock(&r.mtx, func() {
+		r.ids[t.ID] = tokenRevokeUntil(t, r.maxTTL)
+		r.gc()
+	})
+	return nil
+}
+
+func (r *memoryTokenRevoker) RevokeUID(_ context.Context, uid string) error {
+	if uid == "" {
+		return sderr.New("revoke token without uid")
+	}
+	sdconcur.Lock(&r.mtx, func() {
+		r.uids[uid] = sdtime.NowUnixMS()
+	})
+	return nil
+}
+
+func (r *memoryTokenRevoker) RevokeOnce(_ context.Context, t Token) (bool, error) {
+	if t.ID == "" {
+		return false, sderr.New("revoke token without id")
+	}
+	revoked := false
+	sdconcur.Lock(&r.mtx, func() {
+		if r.isRevoked(t) {
+			return
+		}
+		r.ids[t.ID] = tokenRevokeUntil(t, r.maxTTL)
+		r.gc()
+		revoked = true
+	})
+	return revoked, nil
+}
+
+func (r *memoryTokenRevoker) IsRevoked(_ context.Context, t Token) (bool, error) {
+	revoked := false
+	sdconcur.Lock(&r.mtx, func() {
+		revoked = r.isRevoked(t)
+	})
+	return revoked, nil
+}
+
+func (r *memoryTokenRevoker) isRevoked(t Token) bool {
+	if t.ID != "" {
+		if until, ok := r.ids[t.ID]; ok && (until <= 0 || sdtime.NowUnixMS() < until) {
+			return true
+		}
+	}
+	if t.UID != "" {
+		if before, ok := r.uids[t.UID]; ok && t.At <= before {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryTokenRevoker) gc() {
+	r.counter++
+	if r.counter < 1000 {
+		return
+	}
+	r.counter = 0
+	now := sdtime.NowUnixMS()
+	for id, until := range r.ids {
+		if until > 0 && until <= now {
+			delete(r.ids, id)
+		}
+	}
+}
+
+// cache
+
+type cacheTokenRevoker struct {
+	c      sdcache.Cache
+	maxTTL time.Duration
+	mtx    sync.Mutex
+}
+
+const (
+	tokenRevokerKeyPrefixID  = "sdecho.revoked_token."
+	tokenRevokerKeyPrefixUID = "sdecho.revoked_uid."
+)
+
+// NewCacheTokenRevoker 吊销信息保存在c中，key和value均为string，
+// c实现了sdcache.Adder时RevokeOnce使用Add保证多个进程间的原子性，否则只能保证单个进程内的原子性
+func NewCacheTokenRevoker(c sdcache.Cache, maxTTL time.Duration) TokenRevoker {
+	return &cacheTokenRevoker{c: c, maxTTL: maxTTL}
+}
+
+func (r *cacheTokenRevoker) Revoke(ctx context.Context, t Token) error {
+	if t.ID == "" {
+		return sderr.New("revoke token without id")
+	}
+	ttl, ok := r.ttlOf(t)
+	if !ok {
+		return nil
+	}
+	err := r.c.Put(ctx, tokenRevokerKeyPrefixID+t.ID, "1", &sdcache.PutOptions{TTL: ttl})
+	if err != nil {
+		return sderr.Wrap(err, "put revoked token error")
+	}
+	return nil
+}
+
+func (r *cacheTokenRevoker) RevokeOnce(ctx context.Context, t Token) (bool, error) {
+	if t.ID == "" {
+		return false, sderr.New("revoke token without id")
+	}
+	// 先检查uid是否被吊销
+	revoked, err := r.IsRevoked(ctx, Token{UID: t.UID, At: t.At})
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		return false, nil
+	}
+	ttl, ok := r.ttlOf(t)
+	if !ok {
+		// 已经过期
+		return false, nil
+	}
+	key, opts := tokenRevokerKeyPrefixID+t.ID, &sdcache.PutOptions{TTL: ttl}
+	if adder, ok := r.c.(sdcache.Adder); ok {
+		added, err := adder.Add(ctx, key, "1", opts)
+		if err != nil {
+			return false, sderr.Wrap(err, "add revoked token error")
+		}
+		return added, nil
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	revoked, err = r.IsRevoked(ctx, Token{ID: t.ID})
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		return false, nil
+	}
+	if err := r.c.Put(ctx, key, "1", opts); err != nil {
+		return false, sderr.Wrap(err, "put revoked token error")
+	}
+	return true, nil
+}
+
+func (r *cacheTokenRevoker) RevokeUID(ctx context.Context, uid string) error {
+	if uid == "" {
+		return sderr.New("revoke token without uid")
+	}
+	before := strconv.FormatInt(sdtime.NowUnixMS(), 10)
+	err := r.c.Put(ctx, tokenRevokerKeyPrefixUID+uid, before, &sdcache.PutOptions{TTL: r.maxTTL})
+	if err != nil {
+		return sderr.Wrap(err, "put revoked uid error")
+	}
+	return nil
+}
+
+func (r *cacheTokenRevoker) IsRevoked(ctx context.Context, t Token) (bool, error) {
+	if t.ID != "" {
+		_, err := r.c.Get(ctx, tokenRevokerKeyPrefixID+t.ID)
+		if err == nil {
+			return true, nil
+		}
+		if !sderr.Is(err, sdcache.ErrNotFound) {
+			return false, sderr.Wrap(err, "get revoked token error")
+		}
+	}
+	if t.UID != "" {
+		v, err := r.c.Get(ctx, tokenRevokerKeyPrefixUID+t.UID)
+		if err == nil {
+			before, _ := v.(string)
+			if t.At <= sdparse.Int64Def(before, 0) {
+				return true, nil
+			}
+		} else if !sderr.Is(err, sdcache.ErrNotFound) {
+			return false, sderr.Wrap(err, "get revoked uid error")
+		}
+	}
+	return false, nil
+}
+
+// ttlOf 吊销信息的保存时间，token已经过期时返回false
+func (r *cacheTokenRevoker) ttlOf(t Token) (time.Duration, bool) {
+	until := tokenRevokeUntil(t, r.maxTTL)
+	if until <= 0 {
+		return 0, true
+	}
+	ttl := sdtime.Milliseconds(until - sdtime.NowUnixMS())
+	return ttl, ttl > 0
+}
+
+func tokenRevokeUntil(t Token, maxTTL time.Duration) int64 {
+	if t.ExpireAt > 0 {
+		return t.ExpireAt
+	}
+	if maxTTL > 0 {
+		return sdtime.NowUnixMS() + sdtime.ToMillis(maxTTL)
+	}
+	return 0
+}
@@ -0,0 +1,238 @@
+package sdecho
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdtime"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TokenCookie 将token放入HttpOnly cookie，cookie的值为使用Tokens.Secrets签名的token
+// 同时下发一个可被js读取的CSRF cookie，非GET/HEAD/OPTIONS请求需要在CSRFHeader中带上该值(double-submit)，
+// CSRF值与access token绑定，不能被伪造
+type TokenCookie struct {
+	Name        string        // 默认为token，refresh token使用Name+"_refresh"
+	Path        string        // 默认为/
+	RefreshPath string        // refresh token cookie的path，默认为Path
+	Domain      string        //
+	Insecure    bool          // 为true时不设置Secure，仅用于本地开发
+	SameSite    http.SameSite // 默认为Lax
+	CSRFCookie  string        // 默认为csrf_token
+	CSRFHeader  string        // 默认为X-CSRF-Token
+}
+
+func (c TokenCookie) withDefaults() TokenCookie {
+	if c.Name == "" {
+		c.Name = "token"
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.RefreshPath == "" {
+		c.RefreshPath = c.Path
+	}
+	if c.SameSite == 0 {
+		c.SameSite = http.SameSiteLaxMode
+	}
+	if c.CSRFCookie == "" {
+		c.CSRFCookie = "csrf_token"
+	}
+	if c.CSRFHeader == "" {
+		c.CSRFHeader = "X-CSRF-Token"
+	}
+	return c
+}
+
+func (c *TokenCookie) refreshName() string {
+	return c.Name + "_refresh"
+}
+
+// TokenFromBearer 从Authorization: Bearer中获取token，是Tokens的默认方式
+func TokenFromBearer() func(echo.Context) string {
+	return func(ec echo.Context) string {
+		auth := ec.Request().Header.Get(echo.HeaderAuthorization)
+		scheme, encoded, ok := strings.Cut(strings.TrimSpace(auth), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(encoded)
+	}
+}
+
+// TokenFromQuery 从query参数中获取token，token会出现在访问日志中，仅用于无法设置header的场景(例如websocket或者下载链接)
+func TokenFromQuery(name string) func(echo.Context) string {
+	return func(ec echo.Context) string {
+		return ec.QueryParam(name)
+	}
+}
+
+// TokenFromCookie 从Tokens.Cookie中获取token，CSRF校验失败时返回空字符串，请求被视为未登录
+func TokenFromCookie() func(echo.Context) string {
+	return func(ec echo.Context) string {
+		tt, ok := Get[*Tokens](ec, keyTokens)
+		if !ok || tt.Cookie == nil {
+			return ""
+		}
+		cookie, err := ec.Cookie(tt.Cookie.Name)
+		if err != nil || cookie.Value == "" {
+			return ""
+		}
+		if !isSafeMethod(ec.Request().Method) {
+			t, ok := tokenDecode(cookie.Value, tt.Secrets)
+			if !ok || !tt.checkCSRF(ec, t.ID) {
+				return ""
+			}
+		}
+		return cookie.Value
+	}
+}
+
+// TokenFromAny 依次使用getters获取token，返回第一个非空的
+func TokenFromAny(getters ...func(echo.Context) string) func(echo.Context) string {
+	return func(ec echo.Context) string {
+		for _, getter := range getters {
+			if getter == nil {
+				continue
+			}
+			if encoded := getter(ec); encoded != "" {
+				return encoded
+			}
+		}
+		return ""
+	}
+}
+
+// TokenIssueToCookie 签发token并写入Tokens.Cookie
+func TokenIssueToCookie(ctx context.Context, ec echo.Context, t Token) (TokenPair, error) {
+	pair, err := TokenIssueFor(ctx, ec, t)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := TokenSetCookie(ec, pair); err != nil {
+		return TokenPair{}, err
+	}
+	return pair, nil
+}
+
+// TokenSetCookie 将pair和对应的CSRF值写入cookie
+func TokenSetCookie(ec echo.Context, pair TokenPair) error {
+	tt := MustGet[*Tokens](ec, keyTokens)
+	if tt.Cookie == nil {
+		return sderr.New("no token cookie")
+	}
+	t, ok := tokenDecode(pair.Access, tt.Secrets)
+	if !ok {
+		return sderr.WithStack(ErrDecodeToken)
+	}
+	// access token本身带有过期时间，cookie保留到refresh token过期，以便使用cookie刷新
+	expireAt := pair.AccessExpireAt
+	if pair.Refresh != "" {
+		expireAt = pair.RefreshExpireAt
+	}
+	c := tt.Cookie
+	ec.SetCookie(c.cookie(c.Name, c.Path, pair.Access, expireAt, true))
+	ec.SetCookie(c.cookie(c.CSRFCookie, c.Path, csrfSign(tt.Secrets[0], t.ID), expireAt, false))
+	if pair.Refresh != "" {
+		ec.SetCookie(c.cookie(c.refreshName(), c.RefreshPath, pair.Refresh, pair.RefreshExpireAt, true))
+	}
+	return nil
+}
+
+// TokenClearCookie 删除token相关的cookie，用于登出
+func TokenClearCookie(ec echo.Context) {
+	tt := MustGet[*Tokens](ec, keyTokens)
+	if tt.Cookie == nil {
+		return
+	}
+	c := tt.Cookie
+	for _, cookie := range []*http.Cookie{
+		c.cookie(c.Name, c.Path, "", 0, true),
+		c.cookie(c.CSRFCookie, c.Path, "", 0, false),
+		c.cookie(c.refreshName(), c.RefreshPath, "", 0, true),
+	} {
+		cookie.MaxAge = -1
+		ec.SetCookie(cookie)
+	}
+}
+
+// TokenRefreshCookie 使用cookie中的refresh token刷新，并写入新的cookie
+func TokenRefreshCookie(ctx context.Context, ec echo.Context) (TokenPair, error) {
+	tt := MustGet[*Tokens](ec, keyTokens)
+	if tt.Cookie == nil {
+		return TokenPair{}, sderr.New("no token cookie")
+	}
+	refresh, err := ec.Cookie(tt.Cookie.refreshName())
+	if err != nil || refresh.Value == "" {
+		return TokenPair{}, sderr.Wrap(ErrBadRequest, "no refresh token")
+	}
+	var tokenId string
+	if access, err := ec.Cookie(tt.Cookie.Name); err == nil {
+		if t, ok := tokenDecode(access.Value, tt.Secrets); ok {
+			tokenId = t.ID
+		}
+	}
+	if tokenId == "" || !tt.checkCSRF(ec, tokenId) {
+		return TokenPair{}, sderr.Wrap(ErrForbidden, "csrf check failed")
+	}
+	pair, err := TokenRefresh(ctx, ec, refresh.Value)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := TokenSetCookie(ec, pair); err != nil {
+		return TokenPair{}, err
+	}
+	return pair, nil
+}
+
+func (c *TokenCookie) cookie(name, path, value string, expireAt int64, httpOnly bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		Secure:   !c.Insecure,
+		HttpOnly: httpOnly,
+		SameSite: c.SameSite,
+	}
+	if expireAt > 0 {
+		cookie.Expires = sdtime.FromUnixMS(expireAt)
+		cookie.MaxAge = int(time.Until(cookie.Expires).Seconds())
+	}
+	return cookie
+}
+
+func (tt *Tokens) checkCSRF(ec echo.Context, tokenId string) bool {
+	header := ec.Request().Header.Get(tt.Cookie.CSRFHeader)
+	cookie, err := ec.Cookie(tt.Cookie.CSRFCookie)
+	if header == "" || err != nil || subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+		return false
+	}
+	for _, secret := range tt.Secrets {
+		if hmac.Equal([]byte(header), []byte(csrfSign(secret, tokenId))) {
+			return true
+		}
+	}
+	return false
+}
+
+func csrfSign(secret, tokenId string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("csrf:" + tokenId))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
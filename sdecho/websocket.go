@@ -0,0 +1,377 @@
+package sdecho
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gaorx/stardust5/sdconcur"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdslog"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebSocket 在TokenDecode和AccessControlCheck通过后升级为websocket连接，消息使用JSON编码
+type WebSocket[T any] struct {
+	Path         string
+	Object       Object
+	Bare         bool
+	Hub          *WebSocketHub // 为nil则不能使用rooms和按UID广播
+	OnConnect    func(*WebSocketConn) error
+	OnMessage    func(*WebSocketConn, T) error
+	OnClose      func(*WebSocketConn)
+	PingInterval time.Duration // 默认30秒
+	ReadLimit    int64         // 单条消息的最大字节数，默认1MB
+	CheckOrigin  func(r *http.Request) bool
+	Middlewares  []echo.MiddlewareFunc
+	Summary      string
+}
+
+type WebSocketConn struct {
+	ec        echo.Context
+	token     Token
+	conn      *websocket.Conn
+	hub       *WebSocketHub
+	send      chan []byte
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+const (
+	defaultWebSocketPingInterval = 30 * time.Second
+	defaultWebSocketReadLimit    = 1 << 20
+	defaultWebSocketSendBuffer   = 64
+	webSocketWriteWait           = 10 * time.Second
+)
+
+func (ws WebSocket[T]) ToEndpoint() Endpoint {
+	pingInterval := ws.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = defaultWebSocketPingInterval
+	}
+	readLimit := ws.ReadLimit
+	if readLimit <= 0 {
+		readLimit = defaultWebSocketReadLimit
+	}
+	upgrader := websocket.Upgrader{CheckOrigin: ws.CheckOrigin}
+
+	return Endpoint{
+		Methods: []string{http.MethodGet},
+		Path:    ws.Path,
+		Object:  ws.Object,
+		Bare:    ws.Bare,
+		// 升级之前执行endpointCheck，失败时仍然以Result返回
+		Func: func(ec echo.Context) error {
+			routes := MustGet[*Routes](ec, keyRoutes)
+			token, err := endpointCheck(ec, ws.Object, ws.Bare)
+			if err != nil {
+				return ResultErr(err).Write(ec, routes.ResultOptions)
+			}
+			conn, err := upgrader.Upgrade(ec.Response(), ec.Request(), nil)
+			if err != nil {
+				// Upgrade已经写入了错误响应
+				return nil
+			}
+			c := newWebSocketConn(ec, token, conn, ws.Hub)
+			defer c.Close()
+			go c.writeLoop(pingInterval)
+			ws.readLoop(c, pingInterval, readLimit)
+			return nil
+		},
+		Middlewares: ws.Middlewares,
+		Summary:     ws.Summary,
+	}
+}
+
+func (ws WebSocket[T]) readLoop(c *WebSocketConn, pingInterval time.Duration, readLimit int64) {
+	if c.hub != nil {
+		c.hub.add(c)
+	}
+	defer func() {
+		if c.hub != nil {
+			c.hub.remove(c)
+		}
+		if ws.OnClose != nil {
+			ws.OnClose(c)
+		}
+	}()
+
+	c.conn.SetReadLimit(readLimit)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pingInterval * 2))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pingInterval * 2))
+	})
+	if ws.OnConnect != nil {
+		if err := ws.OnConnect(c); err != nil {
+			c.closeWith(websocket.ClosePolicyViolation, err.Error())
+			return
+		}
+	}
+	for {
+		_, raw, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				sdslog.WithError(err).With("path", c.ec.Path()).Debug("read websocket message error")
+			}
+			return
+		}
+		if ws.OnMessage == nil {
+			continue
+		}
+		msg := newAsPtr[T]()
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			_ = c.Send(map[string]any{"error": "illegal message"})
+			continue
+		}
+		if err := ws.OnMessage(c, msg); err != nil {
+			_ = c.Send(map[string]any{"error": err.Error()})
+		}
+	}
+}
+
+func newWebSocketConn(ec echo.Context, token Token, conn *websocket.Conn, hub *WebSocketHub) *WebSocketConn {
+	ctx, cancel := context.WithCancel(ec.Request().Context())
+	return &WebSocketConn{
+		ec:     ec,
+		token:  token,
+		conn:   conn,
+		hub:    hub,
+		send:   make(chan []byte, defaultWebSocketSendBuffer),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (c *WebSocketConn) writeLoop(pingInterval time.Duration) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(webSocketWriteWait))
+			_ = c.conn.Close()
+			return
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(webSocketWriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.cancel()
+				_ = c.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteWait)); err != nil {
+				c.cancel()
+				_ = c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *WebSocketConn) Echo() echo.Context {
+	return c.ec
+}
+
+func (c *WebSocketConn) Token() Token {
+	return c.token
+}
+
+func (c *WebSocketConn) UID() string {
+	return c.token.UID
+}
+
+// Context 在连接关闭后被取消
+func (c *WebSocketConn) Context() context.Context {
+	return c.ctx
+}
+
+// Send 将v编码为JSON后发送，发送缓冲区满时返回错误
+func (c *WebSocketConn) Send(v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return sderr.Wrap(err, "marshal websocket message error")
+	}
+	return c.sendRaw(raw)
+}
+
+func (c *WebSocketConn) sendRaw(raw []byte) error {
+	select {
+	case <-c.ctx.Done():
+		return sderr.New("websocket closed")
+	default:
+	}
+	select {
+	case c.send <- raw:
+		return nil
+	case <-c.ctx.Done():
+		return sderr.New("websocket closed")
+	default:
+		return sderr.New("websocket send buffer full")
+	}
+}
+
+func (c *WebSocketConn) Join(rooms ...string) {
+	if c.hub != nil {
+		c.hub.join(c, rooms)
+	}
+}
+
+func (c *WebSocketConn) Leave(rooms ...string) {
+	if c.hub != nil {
+		c.hub.leave(c, rooms)
+	}
+}
+
+func (c *WebSocketConn) Close() {
+	c.closeOnce.Do(c.cancel)
+}
+
+func (c *WebSocketConn) closeWith(code int, text string) {
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(webSocketWriteWait))
+	c.Close()
+}
+
+// hub
+
+// WebSocketHub 管理连接，连接按token中的UID索引，也可以加入多个room
+type WebSocketHub struct {
+	mtx   sync.RWMutex
+	uids  map[string]map[*WebSocketConn]struct{}
+	rooms map[string]map[*WebSocketConn]struct{}
+	joins map[*WebSocketConn]map[string]struct{}
+}
+
+func NewWebSocketHub() *WebSocketHub {
+	return &WebSocketHub{
+		uids:  map[string]map[*WebSocketConn]struct{}{},
+		rooms: map[string]map[*WebSocketConn]struct{}{},
+		joins: map[*WebSocketConn]map[string]struct{}{},
+	}
+}
+
+// SendToUID 发送给某个UID的所有连接，返回发送成功的连接数
+func (h *WebSocketHub) SendToUID(uid string, v any) (int, error) {
+	return h.sendTo(v, func() map[*WebSocketConn]struct{} { return h.uids[uid] })
+}
+
+// Broadcast 发送给room中的所有连接，返回发送成功的连接数
+func (h *WebSocketHub) Broadcast(room string, v any) (int, error) {
+	return h.sendTo(v, func() map[*WebSocketConn]struct{} { return h.rooms[room] })
+}
+
+// BroadcastAll 发送给所有连接，返回发送成功的连接数
+func (h *WebSocketHub) BroadcastAll(v any) (int, error) {
+	return h.sendTo(v, func() map[*WebSocketConn]struct{} {
+		all := map[*WebSocketConn]struct{}{}
+		for c := range h.joins {
+			all[c] = struct{}{}
+		}
+		return all
+	})
+}
+
+func (h *WebSocketHub) UIDs() []string {
+	var uids []string
+	sdconcur.LockR(&h.mtx, func() {
+		for uid := range h.uids {
+			uids = append(uids, uid)
+		}
+	})
+	return uids
+}
+
+func (h *WebSocketHub) NumConns() int {
+	var n int
+	sdconcur.LockR(&h.mtx, func() {
+		n = len(h.joins)
+	})
+	return n
+}
+
+func (h *WebSocketHub) sendTo(v any, targets func() map[*WebSocketConn]struct{}) (int, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return 0, sderr.Wrap(err, "marshal websocket message error")
+	}
+	var conns []*WebSocketConn
+	sdconcur.LockR(&h.mtx, func() {
+		for c := range targets() {
+			conns = append(conns, c)
+		}
+	})
+	n := 0
+	for _, c := range conns {
+		if c.sendRaw(raw) == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (h *WebSocketHub) add(c *WebSocketConn) {
+	sdconcur.LockW(&h.mtx, func() {
+		h.joins[c] = map[string]struct{}{}
+		if uid := c.UID(); uid != "" {
+			addWebSocketConn(h.uids, uid, c)
+		}
+	})
+}
+
+func (h *WebSocketHub) remove(c *WebSocketConn) {
+	sdconcur.LockW(&h.mtx, func() {
+		for room := range h.joins[c] {
+			removeWebSocketConn(h.rooms, room, c)
+		}
+		delete(h.joins, c)
+		if uid := c.UID(); uid != "" {
+			removeWebSocketConn(h.uids, uid, c)
+		}
+	})
+}
+
+func (h *WebSocketHub) join(c *WebSocketConn, rooms []string) {
+	sdconcur.LockW(&h.mtx, func() {
+		joined, ok := h.joins[c]
+		if !ok {
+			return
+		}
+		for _, room := range rooms {
+			joined[room] = struct{}{}
+			addWebSocketConn(h.rooms, room, c)
+		}
+	})
+}
+
+func (h *WebSocketHub) leave(c *WebSocketConn, rooms []string) {
+	sdconcur.LockW(&h.mtx, func() {
+		joined, ok := h.joins[c]
+		if !ok {
+			return
+		}
+		for _, room := range rooms {
+			delete(joined, room)
+			removeWebSocketConn(h.rooms, room, c)
+		}
+	})
+}
+
+func addWebSocketConn(m map[string]map[*WebSocketConn]struct{}, k string, c *WebSocketConn) {
+	conns, ok := m[k]
+	if !ok {
+		conns = map[*WebSocketConn]struct{}{}
+		m[k] = conns
+	}
+	conns[c] = struct{}{}
+}
+
+func removeWebSocketConn(m map[string]map[*WebSocketConn]struct{}, k string, c *WebSocketConn) {
+	if conns, ok := m[k]; ok {
+		delete(conns, c)
+		if len(conns) <= 0 {
+			delete(m, k)
+		}
+	}
+}
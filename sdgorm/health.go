@@ -0,0 +1,18 @@
+package sdgorm
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"gorm.io/gorm"
+)
+
+// HealthCheck 返回ping数据库的检查函数，可以用于sdecho.Health
+func HealthCheck(db *gorm.DB) func(context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return sderr.Wrap(err, "get gorm sql db error")
+		}
+		return sderr.Wrap(sqlDB.PingContext(ctx), "ping gorm error")
+	}
+}
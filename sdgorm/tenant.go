@@ -0,0 +1,103 @@
+package sdgorm
+
+import (
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdsql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// TenantPlugin 对包含租户列的模型自动添加租户过滤，创建时自动填充租户列
+// 租户ID来自sdsql.WithTenant设置的ctx，需要使用db.WithContext(ctx)；ctx中没有租户时查询会返回sdsql.ErrNoTenant
+type TenantPlugin struct {
+	Column string // 默认为tenant_id
+}
+
+var _ gorm.Plugin = TenantPlugin{}
+
+func (p TenantPlugin) Name() string {
+	return "sdgorm:tenant"
+}
+
+func (p TenantPlugin) Initialize(db *gorm.DB) error {
+	if p.Column == "" {
+		p.Column = "tenant_id"
+	}
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("sdgorm:tenant_query", p.where); err != nil {
+		return sderr.Wrap(err, "register tenant query callback error")
+	}
+	if err := cb.Row().Before("gorm:row").Register("sdgorm:tenant_row", p.where); err != nil {
+		return sderr.Wrap(err, "register tenant row callback error")
+	}
+	if err := cb.Update().Before("gorm:update").Register("sdgorm:tenant_update", p.where); err != nil {
+		return sderr.Wrap(err, "register tenant update callback error")
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("sdgorm:tenant_delete", p.where); err != nil {
+		return sderr.Wrap(err, "register tenant delete callback error")
+	}
+	if err := cb.Create().Before("gorm:create").Register("sdgorm:tenant_create", p.fill); err != nil {
+		return sderr.Wrap(err, "register tenant create callback error")
+	}
+	return nil
+}
+
+func (p TenantPlugin) field(db *gorm.DB) *schema.Field {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil
+	}
+	return db.Statement.Schema.LookUpField(p.Column)
+}
+
+func (p TenantPlugin) where(db *gorm.DB) {
+	field := p.field(db)
+	if field == nil {
+		return
+	}
+	tenant, apply, err := sdsql.TenantFilter(db.Statement.Context)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	if !apply {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenant},
+	}})
+}
+
+func (p TenantPlugin) fill(db *gorm.DB) {
+	field := p.field(db)
+	if field == nil {
+		return
+	}
+	tenant, apply, err := sdsql.TenantFilter(db.Statement.Context)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	if !apply {
+		return
+	}
+	ctx, rv := db.Statement.Context, db.Statement.ReflectValue
+	fillOne := func(v reflect.Value) {
+		if existing, isZero := field.ValueOf(ctx, v); !isZero && existing != tenant {
+			_ = db.AddError(sderr.NewWith("tenant mismatch", existing))
+			return
+		}
+		if err := field.Set(ctx, v, tenant); err != nil {
+			_ = db.AddError(sderr.Wrap(err, "set tenant error"))
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			fillOne(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		fillOne(rv)
+	}
+}
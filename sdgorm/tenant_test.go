@@ -0,0 +1,52 @@
+package sdgorm
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdfile"
+	"github.com/gaorx/stardust5/sdsql"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+type tenantDoc struct {
+	Id       int    `gorm:"column:id;primaryKey;autoIncrement"`
+	TenantId string `gorm:"column:tenant_id"`
+	Title    string `gorm:"column:title"`
+}
+
+func TestTenantPlugin(t *testing.T) {
+	_ = sdfile.UseTempDir("", "", func(dirname string) {
+		db, err := Dial(Address{
+			Driver: "sqlite",
+			DSN:    filepath.Join(dirname, "test.db"),
+		}, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, db.Use(TenantPlugin{}))
+		assert.NoError(t, db.AutoMigrate(&tenantDoc{}))
+
+		ctxA := sdsql.WithTenant(context.Background(), "a")
+		ctxB := sdsql.WithTenant(context.Background(), "b")
+		assert.NoError(t, db.WithContext(ctxA).Create(&tenantDoc{Title: "a1"}).Error)
+		assert.NoError(t, db.WithContext(ctxB).Create([]*tenantDoc{{Title: "b1"}, {Title: "b2"}}).Error)
+		assert.Error(t, db.WithContext(ctxA).Create(&tenantDoc{Title: "x", TenantId: "b"}).Error)
+
+		// 没有租户
+		var docs []*tenantDoc
+		err = db.Find(&docs).Error
+		assert.True(t, sderr.Is(err, sdsql.ErrNoTenant))
+
+		docs, err = Find[*tenantDoc](db.WithContext(ctxB))
+		assert.NoError(t, err)
+		assert.Len(t, docs, 2)
+		assert.True(t, docs[0].TenantId == "b" && docs[1].TenantId == "b")
+
+		n := db.WithContext(ctxA).Where("1 = 1").Delete(&tenantDoc{}).RowsAffected
+		assert.Equal(t, int64(1), n)
+
+		docs, err = Find[*tenantDoc](db.WithContext(sdsql.WithoutTenant(context.Background())))
+		assert.NoError(t, err)
+		assert.Len(t, docs, 2)
+	})
+}
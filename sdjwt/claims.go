@@ -0,0 +1,158 @@
+package sdjwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/samber/lo"
+
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/gaorx/stardust5/sdjson"
+)
+
+type EncodeOptions struct {
+	TTL       time.Duration // exp = iat + TTL，为0则不设置exp
+	NotBefore time.Time
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ID        string // jti
+	Now       func() time.Time
+}
+
+type DecodeOptions struct {
+	Leeway     time.Duration // 校验exp/nbf/iat时允许的时钟偏差
+	Issuer     string        // 不为空时校验iss
+	Audience   string        // 不为空时校验aud
+	RequireExp bool
+	Now        func() time.Time
+}
+
+func EncodeWith(key *Key, payload any, opts *EncodeOptions) (string, error) {
+	if key == nil {
+		return "", sderr.WithStack(ErrNoKey)
+	}
+	opts1 := lo.FromPtr(opts)
+	m, err := key.signingMethod()
+	if err != nil {
+		return "", sderr.WithStack(err)
+	}
+	if err := key.check(true); err != nil {
+		return "", sderr.WithStack(err)
+	}
+	claims, err := sdjson.StructToObject(payload)
+	if err != nil {
+		return "", sderr.Wrap(err, "struct to claims error")
+	}
+	if claims == nil {
+		claims = sdjson.Object{}
+	}
+	now := nowOf(opts1.Now)
+	claims["iat"] = now.Unix()
+	if opts1.TTL > 0 {
+		claims["exp"] = now.Add(opts1.TTL).Unix()
+	}
+	if !opts1.NotBefore.IsZero() {
+		claims["nbf"] = opts1.NotBefore.Unix()
+	}
+	if opts1.Issuer != "" {
+		claims["iss"] = opts1.Issuer
+	}
+	if opts1.Subject != "" {
+		claims["sub"] = opts1.Subject
+	}
+	if len(opts1.Audience) == 1 {
+		claims["aud"] = opts1.Audience[0]
+	} else if len(opts1.Audience) > 1 {
+		claims["aud"] = opts1.Audience
+	}
+	if opts1.ID != "" {
+		claims["jti"] = opts1.ID
+	}
+	rawToken := jwt.NewWithClaims(m, jwt.MapClaims(claims))
+	if key.ID != "" {
+		rawToken.Header["kid"] = key.ID
+	}
+	signedToken, err := rawToken.SignedString(key.SignKey)
+	if err != nil {
+		return "", sderr.Wrap(err, "encode jwt token error")
+	}
+	return signedToken, nil
+}
+
+func DecodeWith[T any](keys KeySet, signedToken string, opts *DecodeOptions) (T, error) {
+	claims, err := DecodeClaims(keys, signedToken, opts)
+	if err != nil {
+		return lo.Empty[T](), err
+	}
+	return sdjson.ObjectToStruct[T](claims)
+}
+
+func DecodeClaims(keys KeySet, signedToken string, opts *DecodeOptions) (sdjson.Object, error) {
+	opts1 := lo.FromPtr(opts)
+	parser := jwt.Parser{SkipClaimsValidation: true}
+
+	// 先不验证签名解析出header，根据kid和alg选择密钥
+	unverified, _, err := parser.ParseUnverified(signedToken, jwt.MapClaims{})
+	if err != nil {
+		return nil, sderr.Wrap(err, "decode jwt token error")
+	}
+	kid, _ := unverified.Header["kid"].(string)
+	candidates := keys.candidates(kid, unverified.Method.Alg())
+	if len(candidates) <= 0 {
+		return nil, sderr.WithStack(ErrNoKey)
+	}
+
+	var claims jwt.MapClaims
+	var lastErr error
+	for _, key := range candidates {
+		if err := key.check(false); err != nil {
+			lastErr = err
+			continue
+		}
+		claims0 := jwt.MapClaims{}
+		_, err := parser.ParseWithClaims(signedToken, claims0, func(*jwt.Token) (any, error) {
+			return key.VerifyKey, nil
+		})
+		if err == nil {
+			claims = claims0
+			break
+		}
+		lastErr = err
+	}
+	if claims == nil {
+		return nil, sderr.Wrap(lastErr, "decode jwt token error")
+	}
+	if err := verifyClaims(claims, opts1); err != nil {
+		return nil, err
+	}
+	return sdjson.Object(claims), nil
+}
+
+func verifyClaims(claims jwt.MapClaims, opts DecodeOptions) error {
+	now := nowOf(opts.Now)
+	leeway := opts.Leeway
+	if !claims.VerifyExpiresAt(now.Add(-leeway).Unix(), opts.RequireExp) {
+		return sderr.WithStack(ErrExpired)
+	}
+	if !claims.VerifyNotBefore(now.Add(leeway).Unix(), false) {
+		return sderr.WithStack(ErrNotValidYet)
+	}
+	if !claims.VerifyIssuedAt(now.Add(leeway).Unix(), false) {
+		return sderr.WithStack(ErrNotValidYet)
+	}
+	if opts.Issuer != "" && !claims.VerifyIssuer(opts.Issuer, true) {
+		return sderr.WithStack(ErrIssuer)
+	}
+	if opts.Audience != "" && !claims.VerifyAudience(opts.Audience, true) {
+		return sderr.WithStack(ErrAudience)
+	}
+	return nil
+}
+
+func nowOf(now func() time.Time) time.Time {
+	if now != nil {
+		return now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,88 @@
+package sdjwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gaorx/stardust5/sderr"
+)
+
+type claimsUser struct {
+	UID string `json:"uid"`
+}
+
+func TestEncodeWithAndDecodeWith(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	keys := []*Key{
+		HmacKey("h1", HS256, "QphlY11dKQ24IoZr"),
+		{ID: "r1", Method: RS256, SignKey: rsaKey, VerifyKey: &rsaKey.PublicKey},
+		{ID: "e1", Method: ES256, SignKey: ecKey, VerifyKey: &ecKey.PublicKey},
+		{ID: "d1", Method: EdDSA, SignKey: edKey, VerifyKey: edKey.Public()},
+	}
+	u0 := claimsUser{UID: "3939939"}
+	for _, key := range keys {
+		token, err := EncodeWith(key, u0, &EncodeOptions{TTL: time.Minute, Issuer: "iss1", Audience: []string{"aud1"}})
+		assert.NoError(t, err)
+		u1, err := DecodeWith[claimsUser](KeySet{key.Public()}, token, &DecodeOptions{Issuer: "iss1", Audience: "aud1"})
+		assert.NoError(t, err)
+		assert.Equal(t, u0, u1)
+	}
+
+	// verify claims
+	key := keys[0]
+	token, err := EncodeWith(key, u0, &EncodeOptions{TTL: time.Minute, Issuer: "iss1", Audience: []string{"aud1"}})
+	assert.NoError(t, err)
+	_, err = DecodeWith[claimsUser](KeySet{key}, token, &DecodeOptions{Issuer: "iss2"})
+	assert.True(t, sderr.Is(err, ErrIssuer))
+	_, err = DecodeWith[claimsUser](KeySet{key}, token, &DecodeOptions{Audience: "aud2"})
+	assert.True(t, sderr.Is(err, ErrAudience))
+	later := func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = DecodeWith[claimsUser](KeySet{key}, token, &DecodeOptions{Now: later})
+	assert.True(t, sderr.Is(err, ErrExpired))
+	_, err = DecodeWith[claimsUser](KeySet{key}, token, &DecodeOptions{Now: later, Leeway: 2 * time.Minute})
+	assert.NoError(t, err)
+
+	// rotation by kid
+	_, err = DecodeWith[claimsUser](KeySet{HmacKey("h2", HS256, "another")}, token, nil)
+	assert.True(t, sderr.Is(err, ErrNoKey))
+	_, err = DecodeWith[claimsUser](KeySet{HmacKey("h2", HS256, "another"), key}, token, nil)
+	assert.NoError(t, err)
+}
+
+func TestParsePemKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	privPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	pubDer, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.NoError(t, err)
+	pubPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer})
+
+	signKey, err := ParsePemKey("r1", RS256, privPem)
+	assert.NoError(t, err)
+	verifyKey, err := ParsePemKey("r1", RS256, pubPem)
+	assert.NoError(t, err)
+	assert.Nil(t, verifyKey.SignKey)
+
+	token, err := EncodeWith(signKey, claimsUser{UID: "u1"}, nil)
+	assert.NoError(t, err)
+	u, err := DecodeWith[claimsUser](KeySet{verifyKey}, token, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", u.UID)
+	_, err = EncodeWith(verifyKey, claimsUser{UID: "u1"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,13 @@
+package sdjwt
+
+import (
+	"github.com/gaorx/stardust5/sderr"
+)
+
+var (
+	ErrNoKey       = sderr.Sentinel("no matched jwt key")
+	ErrExpired     = sderr.Sentinel("jwt token expired")
+	ErrNotValidYet = sderr.Sentinel("jwt token not valid yet")
+	ErrIssuer      = sderr.Sentinel("jwt token issuer mismatch")
+	ErrAudience    = sderr.Sentinel("jwt token audience mismatch")
+)
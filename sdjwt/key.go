@@ -0,0 +1,155 @@
+package sdjwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/gaorx/stardust5/sderr"
+)
+
+const (
+	HS256 = "HS256"
+	HS384 = "HS384"
+	HS512 = "HS512"
+	RS256 = "RS256"
+	RS384 = "RS384"
+	RS512 = "RS512"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	ES512 = "ES512"
+	EdDSA = "EdDSA"
+)
+
+// Key 签名和验证使用的密钥，ID对应jwt header中的kid
+// 对于HMAC，SignKey和VerifyKey都是secret([]byte)
+// 对于非对称算法，SignKey是私钥，VerifyKey是公钥，只用于验证时可以没有SignKey
+type Key struct {
+	ID        string
+	Method    string
+	SignKey   any
+	VerifyKey any
+}
+
+type KeySet []*Key
+
+func HmacKey(id, method, secret string) *Key {
+	if method == "" {
+		method = HS256
+	}
+	return &Key{
+		ID:        id,
+		Method:    method,
+		SignKey:   []byte(secret),
+		VerifyKey: []byte(secret),
+	}
+}
+
+func ParsePemKey(id, method string, pemData []byte) (*Key, error) {
+	k := &Key{ID: id, Method: method}
+	switch {
+	case strings.HasPrefix(method, "RS"):
+		if priv, err := jwt.ParseRSAPrivateKeyFromPEM(pemData); err == nil {
+			k.SignKey, k.VerifyKey = priv, &priv.PublicKey
+		} else if pub, err := jwt.ParseRSAPublicKeyFromPEM(pemData); err == nil {
+			k.VerifyKey = pub
+		} else {
+			return nil, sderr.WrapWith(err, "parse rsa pem key error", id)
+		}
+	case strings.HasPrefix(method, "ES"):
+		if priv, err := jwt.ParseECPrivateKeyFromPEM(pemData); err == nil {
+			k.SignKey, k.VerifyKey = priv, &priv.PublicKey
+		} else if pub, err := jwt.ParseECPublicKeyFromPEM(pemData); err == nil {
+			k.VerifyKey = pub
+		} else {
+			return nil, sderr.WrapWith(err, "parse ecdsa pem key error", id)
+		}
+	case method == EdDSA:
+		if priv, err := jwt.ParseEdPrivateKeyFromPEM(pemData); err == nil {
+			k.SignKey, k.VerifyKey = priv, priv.(crypto.Signer).Public()
+		} else if pub, err := jwt.ParseEdPublicKeyFromPEM(pemData); err == nil {
+			k.VerifyKey = pub
+		} else {
+			return nil, sderr.WrapWith(err, "parse ed25519 pem key error", id)
+		}
+	default:
+		return nil, sderr.NewWith("illegal pem key method", method)
+	}
+	return k, nil
+}
+
+func LoadPemKey(id, method, filename string) (*Key, error) {
+	pemData, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, sderr.WrapWith(err, "read pem key error", filename)
+	}
+	return ParsePemKey(id, method, pemData)
+}
+
+func (k *Key) Public() *Key {
+	return &Key{ID: k.ID, Method: k.Method, VerifyKey: k.VerifyKey}
+}
+
+func (k *Key) signingMethod() (jwt.SigningMethod, error) {
+	m := jwt.GetSigningMethod(k.Method)
+	if m == nil {
+		return nil, sderr.NewWith("illegal signing method", k.Method)
+	}
+	return m, nil
+}
+
+func (k *Key) check(sign bool) error {
+	key := k.VerifyKey
+	if sign {
+		key = k.SignKey
+	}
+	if key == nil {
+		return sderr.NewWith("no key", k.ID)
+	}
+	var ok bool
+	switch {
+	case strings.HasPrefix(k.Method, "HS"):
+		_, ok = key.([]byte)
+	case strings.HasPrefix(k.Method, "RS"):
+		if sign {
+			_, ok = key.(*rsa.PrivateKey)
+		} else {
+			_, ok = key.(*rsa.PublicKey)
+		}
+	case strings.HasPrefix(k.Method, "ES"):
+		if sign {
+			_, ok = key.(*ecdsa.PrivateKey)
+		} else {
+			_, ok = key.(*ecdsa.PublicKey)
+		}
+	case k.Method == EdDSA:
+		if sign {
+			_, ok = key.(ed25519.PrivateKey)
+		} else {
+			_, ok = key.(ed25519.PublicKey)
+		}
+	}
+	if !ok {
+		return sderr.NewWith("illegal key type", k.ID)
+	}
+	return nil
+}
+
+func (ks KeySet) candidates(kid, alg string) []*Key {
+	var r []*Key
+	for _, k := range ks {
+		if k == nil || k.Method != alg {
+			continue
+		}
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		r = append(r, k)
+	}
+	return r
+}
@@ -0,0 +1,16 @@
+package sdmqtt
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+)
+
+// HealthCheck 返回检查是否已连接的函数，可以用于sdecho.Health
+func HealthCheck(client *Client) func(context.Context) error {
+	return func(context.Context) error {
+		if client.Client == nil || !client.IsConnectionOpen() {
+			return sderr.New("MQTT client not connected")
+		}
+		return nil
+	}
+}
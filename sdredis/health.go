@@ -0,0 +1,14 @@
+package sdredis
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/redis/go-redis/v9"
+)
+
+// HealthCheck 返回ping redis的检查函数，可以用于sdecho.Health
+func HealthCheck(client redis.UniversalClient) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return sderr.Wrap(client.Ping(ctx).Err(), "ping redis error")
+	}
+}
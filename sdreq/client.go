@@ -83,5 +83,9 @@ func New(opts *Options) *req.Client {
 	if opts1.RetryCount > 0 {
 		c.SetCommonRetryCount(opts1.RetryCount)
 	}
+	c.OnBeforeRequest(func(_ *req.Client, request *req.Request) error {
+		injectTrace(request)
+		return nil
+	})
 	return c
 }
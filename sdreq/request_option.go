@@ -10,6 +10,7 @@ func applyOptions(request *req.Request, opts []RequestOption) *req.Request {
 	for _, opt := range opts {
 		request = opt(request)
 	}
+	injectTrace(request)
 	return request
 }
 
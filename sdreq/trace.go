@@ -0,0 +1,17 @@
+package sdreq
+
+import (
+	"github.com/gaorx/stardust5/sdtrace"
+	"github.com/imroc/req/v3"
+)
+
+// injectTrace 将请求ctx中的sdtrace.Trace转发给下游
+func injectTrace(request *req.Request) {
+	if t, ok := sdtrace.Of(request.Context()); ok {
+		t.Inject(func(k, v string) {
+			if request.Headers.Get(k) == "" {
+				request.SetHeader(k, v)
+			}
+		})
+	}
+}
@@ -13,7 +13,7 @@ type Logger struct {
 
 func L(l *slog.Logger) Logger {
 	if l == nil {
-		l = slog.Default()
+		l = defaultLogger()
 	}
 	return Logger{l}
 }
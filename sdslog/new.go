@@ -56,7 +56,7 @@ func New(opts *Options) (*slog.Logger, error) {
 	}
 
 	// go
-	return slog.New(h), nil
+	return slog.New(TraceHandler(h)), nil
 }
 
 func newWriter(outputs []string) (io.Writer, error) {
@@ -0,0 +1,48 @@
+package sdslog
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sdtrace"
+	"log/slog"
+)
+
+// TraceHandler 对带有sdtrace.Trace的ctx自动添加request_id、trace_id和span_id，New创建的logger已经使用
+func TraceHandler(h slog.Handler) slog.Handler {
+	if _, ok := h.(traceHandler); ok {
+		return h
+	}
+	return traceHandler{h}
+}
+
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if t, ok := sdtrace.Of(ctx); ok {
+		r = r.Clone()
+		r.AddAttrs(
+			slog.String("request_id", t.RequestID),
+			slog.String("trace_id", t.TraceID),
+			slog.String("span_id", t.SpanID),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
+
+// defaultLogger 没有使用Setup时也能输出trace
+func defaultLogger() *slog.Logger {
+	l := slog.Default()
+	if _, ok := l.Handler().(traceHandler); !ok {
+		l = slog.New(traceHandler{l.Handler()})
+	}
+	return l
+}
@@ -0,0 +1,53 @@
+package sdsql
+
+import (
+	"context"
+	"github.com/gaorx/stardust5/sderr"
+)
+
+var (
+	ErrNoTenant = sderr.Sentinel("no tenant in context")
+)
+
+type tenantKey struct{}
+
+type tenantVal struct {
+	id   string
+	skip bool
+}
+
+// WithTenant 将租户ID放入ctx，sdgorm.TenantPlugin和sdbun.TenantModel会据此自动过滤
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantVal{id: tenant})
+}
+
+// WithoutTenant 跳过租户过滤，用于后台任务或者跨租户的管理功能
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantVal{skip: true})
+}
+
+func TenantOf(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	v, ok := ctx.Value(tenantKey{}).(tenantVal)
+	if !ok || v.skip || v.id == "" {
+		return "", false
+	}
+	return v.id, true
+}
+
+// TenantFilter 返回需要过滤的租户ID；使用WithoutTenant时apply为false；ctx中没有租户时返回ErrNoTenant
+func TenantFilter(ctx context.Context) (tenant string, apply bool, err error) {
+	if ctx != nil {
+		if v, ok := ctx.Value(tenantKey{}).(tenantVal); ok {
+			if v.skip {
+				return "", false, nil
+			}
+			if v.id != "" {
+				return v.id, true, nil
+			}
+		}
+	}
+	return "", false, sderr.WithStack(ErrNoTenant)
+}
@@ -0,0 +1,159 @@
+package sdsqlparser
+
+import (
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+	"github.com/gaorx/stardust5/sderr"
+	"strings"
+)
+
+// NamedArgs 返回SQL中@name形式的命名参数，按照第一次出现的顺序，不包括字符串、注释中的和@@系统变量
+func NamedArgs(q string) []string {
+	var names []string
+	for _, arg := range scanNamedArgs(q) {
+		if !contains(names, arg.name) {
+			names = append(names, arg.name)
+		}
+	}
+	return names
+}
+
+// ReplaceNamedArgs 替换SQL中的命名参数，如果参数被括号包围(例如IN (@ids))，parenthesized为true，此时括号也会被替换
+func ReplaceNamedArgs(q string, f func(name string, parenthesized bool) string) string {
+	args := scanNamedArgs(q)
+	if len(args) <= 0 {
+		return q
+	}
+	var b strings.Builder
+	last := 0
+	for _, arg := range args {
+		start, end := arg.start, arg.end
+		if arg.parenthesized() {
+			start, end = arg.parenStart, arg.parenEnd
+		}
+		b.WriteString(q[last:start])
+		b.WriteString(f(arg.name, arg.parenthesized()))
+		last = end
+	}
+	b.WriteString(q[last:])
+	return b.String()
+}
+
+// Check 检查SQL的语法，@name会被当作参数，IN @name会被当作列表参数
+func Check(q string) error {
+	args := scanNamedArgs(q)
+	var b strings.Builder
+	last := 0
+	for _, arg := range args {
+		b.WriteString(q[last:arg.start])
+		if arg.afterIn && !arg.parenthesized() {
+			b.WriteString("::" + arg.name)
+		} else {
+			b.WriteString(":" + arg.name)
+		}
+		last = arg.end
+	}
+	b.WriteString(q[last:])
+	if _, err := sqlparser.Parse(b.String()); err != nil {
+		return sderr.WrapWith(err, "check SQL error", q)
+	}
+	return nil
+}
+
+type namedArg struct {
+	name                 string
+	start, end           int
+	parenStart, parenEnd int // 没有括号时为-1
+	afterIn              bool
+}
+
+func (arg namedArg) parenthesized() bool {
+	return arg.parenStart >= 0
+}
+
+func scanNamedArgs(q string) []namedArg {
+	var args []namedArg
+	n := len(q)
+	for i := 0; i < n; {
+		c := q[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(q, i)
+		case c == '-' && i+1 < n && q[i+1] == '-':
+			for i < n && q[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < n && q[i+1] == '*':
+			if end := strings.Index(q[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = n
+			}
+		case c == '@' && i+1 < n && q[i+1] == '@':
+			i += 2
+			for i < n && isIdentChar(q[i]) {
+				i++
+			}
+		case c == '@' && i+1 < n && isIdentStart(q[i+1]):
+			j := i + 1
+			for j < n && isIdentChar(q[j]) {
+				j++
+			}
+			arg := namedArg{name: q[i+1 : j], start: i, end: j, parenStart: -1, parenEnd: -1}
+			before := strings.TrimRight(q[:i], " \t\r\n")
+			after := strings.TrimLeft(q[j:], " \t\r\n")
+			if strings.HasSuffix(before, "(") && strings.HasPrefix(after, ")") {
+				arg.parenStart = len(before) - 1
+				arg.parenEnd = n - len(after) + 1
+				before = strings.TrimRight(before[:len(before)-1], " \t\r\n")
+			}
+			if len(before) >= 2 && strings.EqualFold(before[len(before)-2:], "in") &&
+				(len(before) == 2 || !isIdentChar(before[len(before)-3])) {
+				arg.afterIn = true
+			}
+			args = append(args, arg)
+			i = j
+		default:
+			i++
+		}
+	}
+	return args
+}
+
+func skipQuoted(q string, i int) int {
+	quote := q[i]
+	i++
+	for i < len(q) {
+		switch q[i] {
+		case '\\':
+			if quote != '`' {
+				i += 2
+				continue
+			}
+		case quote:
+			if i+1 < len(q) && q[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func contains(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package sdsqlparser
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func TestNamedArgs(t *testing.T) {
+	for _, c := range []struct {
+		q        string
+		expected []string
+	}{
+		{"SELECT * FROM t", nil},
+		{"SELECT * FROM t WHERE a = @a AND b = @b_1", []string{"a", "b_1"}},
+		{"SELECT * FROM t WHERE a = @a OR a2 = @a", []string{"a"}},
+		{"SELECT * FROM t WHERE a = '@x' AND b = \"@y\" AND `@z` = @c", []string{"c"}},
+		{"SELECT * FROM t WHERE a = 'it''s @x' AND b = 'a\\'@y' AND c = @c", []string{"c"}},
+		{"SELECT * FROM t -- @x\nWHERE a = @a /* @y */", []string{"a"}},
+		{"SELECT * FROM t WHERE a = @a /* @y", []string{"a"}},
+		{"SELECT @@version, @@session.sql_mode, @a", []string{"a"}},
+		{"SELECT * FROM t WHERE a = @1 OR b = @", nil},
+		{"SELECT * FROM t WHERE id IN (@ids) AND x IN @xs", []string{"ids", "xs"}},
+	} {
+		assert.Equal(t, c.expected, NamedArgs(c.q), c.q)
+	}
+}
+
+func TestReplaceNamedArgs(t *testing.T) {
+	mark := func(name string, parenthesized bool) string {
+		return "<" + name + ":" + strconv.FormatBool(parenthesized) + ">"
+	}
+	for _, c := range []struct {
+		q, expected string
+	}{
+		{"SELECT * FROM t", "SELECT * FROM t"},
+		{"SELECT * FROM t WHERE a = @a", "SELECT * FROM t WHERE a = <a:false>"},
+		{"SELECT * FROM t WHERE id IN (@ids)", "SELECT * FROM t WHERE id IN <ids:true>"},
+		{"SELECT * FROM t WHERE id IN ( @ids )", "SELECT * FROM t WHERE id IN <ids:true>"},
+		{"SELECT * FROM t WHERE id IN @ids", "SELECT * FROM t WHERE id IN <ids:false>"},
+		{"SELECT * FROM t WHERE id IN (@a, @b)", "SELECT * FROM t WHERE id IN (<a:false>, <b:false>)"},
+		{"SELECT * FROM t WHERE a = '@x' -- @y\nAND b = @b", "SELECT * FROM t WHERE a = '@x' -- @y\nAND b = <b:false>"},
+		{"SELECT @@version, @v", "SELECT @@version, <v:false>"},
+	} {
+		assert.Equal(t, c.expected, ReplaceNamedArgs(c.q, mark), c.q)
+	}
+}
+
+func TestScanNamedArgsAfterIn(t *testing.T) {
+	for _, c := range []struct {
+		q       string
+		afterIn bool
+	}{
+		{"SELECT * FROM t WHERE id IN @ids", true},
+		{"SELECT * FROM t WHERE id in (@ids)", true},
+		{"SELECT * FROM t WHERE id = @ids", false},
+		{"SELECT * FROM t WHERE join_in = @ids", false},
+		{"SELECT * FROM t WHERE login @ids", false},
+	} {
+		args := scanNamedArgs(c.q)
+		if assert.Len(t, args, 1, c.q) {
+			assert.Equal(t, c.afterIn, args[0].afterIn, c.q)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check("SELECT * FROM t WHERE a = @a AND id IN @ids"))
+	assert.NoError(t, Check("SELECT * FROM t WHERE id IN (@ids)"))
+	assert.Error(t, Check("SELECT * FROM WHERE a = @a"))
+}
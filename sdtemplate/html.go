@@ -35,11 +35,15 @@ func (te htmlExecutor) ExecDef(tmpl string, data any, def string) string {
 }
 
 func HtmlLoad(fsys fs.FS, name string) (*template.Template, error) {
+	return htmlLoad(fsys, name, nil)
+}
+
+func htmlLoad(fsys fs.FS, name string, funcs template.FuncMap) (*template.Template, error) {
 	data, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return nil, sderr.WrapWith(err, "read template error", name)
 	}
-	t, err := template.New(name).Parse(string(data))
+	t, err := template.New(name).Funcs(funcs).Parse(string(data))
 	if err != nil {
 		return nil, sderr.WrapWith(err, "parse template error", name)
 	}
@@ -55,6 +59,7 @@ type HtmlLoader struct {
 type HtmlLoaderOptions struct {
 	Eager      bool
 	Extensions []string
+	Funcs      template.FuncMap // 解析模板前注册的函数
 }
 
 func NewHtmlLoader(fsys fs.FS, opts *HtmlLoaderOptions) (*HtmlLoader, error) {
@@ -85,7 +90,7 @@ func NewHtmlLoader(fsys fs.FS, opts *HtmlLoaderOptions) (*HtmlLoader, error) {
 			return nil
 		})
 		for _, filename := range filenames {
-			t, err := HtmlLoad(fsys, filename)
+			t, err := htmlLoad(fsys, filename, opts1.Funcs)
 			if err != nil {
 				return nil, err
 			}
@@ -104,6 +109,6 @@ func (loader *HtmlLoader) Load(name string) (*template.Template, error) {
 		}
 		return nil, sderr.NewWith("not found template", name)
 	} else {
-		return HtmlLoad(loader.fsys, name)
+		return htmlLoad(loader.fsys, name, loader.options.Funcs)
 	}
 }
@@ -2,7 +2,10 @@ package sdtemplate
 
 import (
 	"github.com/stretchr/testify/assert"
+	"html/template"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestHtmlTemplate(t *testing.T) {
@@ -15,3 +18,19 @@ func TestHtmlTemplate(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "&lt;hello&gt;", s)
 }
+
+func TestHtmlLoaderFuncs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.gohtml": &fstest.MapFile{Data: []byte(`{{upper .Name}}`)},
+	}
+	loader, err := NewHtmlLoader(fsys, &HtmlLoaderOptions{
+		Funcs: template.FuncMap{"upper": strings.ToUpper},
+	})
+	assert.NoError(t, err)
+	tmpl, err := loader.Load("a.gohtml")
+	assert.NoError(t, err)
+	var buff strings.Builder
+	err = tmpl.Execute(&buff, map[string]any{"Name": "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "HELLO", buff.String())
+}
@@ -0,0 +1,2 @@
+// Package sdtrace 请求ID和W3C trace context的传递
+package sdtrace
@@ -0,0 +1,132 @@
+package sdtrace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceparent = "traceparent"
+)
+
+// Trace 当前服务处理请求时的trace信息，SpanID为当前服务的span，ParentID为上游的span
+type Trace struct {
+	RequestID string
+	TraceID   string // 32位hex
+	SpanID    string // 16位hex
+	ParentID  string // 16位hex，没有上游时为空
+	Flags     byte
+}
+
+const flagSampled = 0x01
+
+// New 生成新的trace
+func New() Trace {
+	traceId := randomHex(16)
+	return Trace{
+		RequestID: traceId,
+		TraceID:   traceId,
+		SpanID:    randomHex(8),
+		Flags:     flagSampled,
+	}
+}
+
+// Extract 从上游的header中生成当前服务的trace，header中没有或者不合法时生成新的
+func Extract(get func(k string) string) Trace {
+	t := New()
+	if traceId, parentId, flags, ok := ParseTraceparent(get(HeaderTraceparent)); ok {
+		t.TraceID, t.ParentID, t.Flags = traceId, parentId, flags
+		t.RequestID = traceId
+	}
+	if requestId := get(HeaderRequestID); isValidRequestID(requestId) {
+		t.RequestID = requestId
+	}
+	return t
+}
+
+// Inject 将trace写入下游请求的header
+func (t Trace) Inject(set func(k, v string)) {
+	if t.RequestID != "" {
+		set(HeaderRequestID, t.RequestID)
+	}
+	if traceparent := t.Traceparent(); traceparent != "" {
+		set(HeaderTraceparent, traceparent)
+	}
+}
+
+// Traceparent 返回下游请求使用的traceparent，parent-id为当前服务的SpanID
+func (t Trace) Traceparent() string {
+	if !isHex(t.TraceID, 32) || !isHex(t.SpanID, 16) {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", t.TraceID, t.SpanID, t.Flags)
+}
+
+// ParseTraceparent 解析W3C traceparent，格式为version-trace_id-parent_id-flags
+func ParseTraceparent(s string) (traceId, parentId string, flags byte, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return "", "", 0, false
+	}
+	version, traceId, parentId := parts[0], parts[1], parts[2]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", "", 0, false
+	}
+	if !isHex(traceId, 32) || isZeroHex(traceId) || !isHex(parentId, 16) || isZeroHex(parentId) || !isHex(parts[3], 2) {
+		return "", "", 0, false
+	}
+	b, _ := hex.DecodeString(parts[3])
+	return traceId, parentId, b[0], true
+}
+
+type traceKey struct{}
+
+func With(ctx context.Context, t Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+func Of(ctx context.Context) (Trace, bool) {
+	if ctx == nil {
+		return Trace{}, false
+	}
+	t, ok := ctx.Value(traceKey{}).(Trace)
+	return t, ok
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+func isValidRequestID(s string) bool {
+	if s == "" || len(s) > 128 {
+		return false
+	}
+	for _, c := range s {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package sdtrace
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	// 没有上游
+	t1 := Extract(func(string) string { return "" })
+	assert.Len(t, t1.TraceID, 32)
+	assert.Len(t, t1.SpanID, 16)
+	assert.Equal(t, "", t1.ParentID)
+	assert.Equal(t, t1.TraceID, t1.RequestID)
+
+	// 有上游
+	h := http.Header{}
+	h.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Set(HeaderRequestID, "req-1")
+	t2 := Extract(h.Get)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", t2.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", t2.ParentID)
+	assert.NotEqual(t, t2.ParentID, t2.SpanID)
+	assert.Equal(t, byte(1), t2.Flags)
+	assert.Equal(t, "req-1", t2.RequestID)
+
+	// 下游
+	out := http.Header{}
+	t2.Inject(out.Set)
+	assert.Equal(t, "req-1", out.Get(HeaderRequestID))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+t2.SpanID+"-01", out.Get(HeaderTraceparent))
+
+	// 不合法
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		_, _, _, ok := ParseTraceparent(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := Of(context.Background())
+	assert.False(t, ok)
+	t1 := New()
+	t2, ok := Of(With(context.Background(), t1))
+	assert.True(t, ok)
+	assert.Equal(t, t1, t2)
+}
@@ -3,11 +3,35 @@ package sdvalidator
 import (
 	"context"
 	"github.com/gaorx/stardust5/sderr"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var defaultValidate = validator.New()
+var (
+	defaultValidate = validator.New()
+	jsonValidate    = NewJSON()
+)
+
+// NewJSON 创建一个validator，错误中的字段名使用json tag中的名称，用于校验JSON请求
+func NewJSON() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonFieldName)
+	return v
+}
+
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	default:
+		return name
+	}
+}
 
 func Default() *validator.Validate {
 	return defaultValidate
@@ -17,6 +41,11 @@ func SetDefault(v *validator.Validate) {
 	defaultValidate = v
 }
 
+// JSON 错误中的字段名使用json tag中名称的validator
+func JSON() *validator.Validate {
+	return jsonValidate
+}
+
 func FieldsErrors(err error) []validator.FieldError {
 	if err == nil {
 		return nil
@@ -0,0 +1,111 @@
+package sdvalidator
+
+import (
+	"github.com/gaorx/stardust5/sderr"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entrans "github.com/go-playground/validator/v10/translations/en"
+	zhtrans "github.com/go-playground/validator/v10/translations/zh"
+	"strings"
+	"sync"
+)
+
+const (
+	LocaleEn = "en"
+	LocaleZh = "zh"
+)
+
+type FieldErrorInfo struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+var (
+	translatorsMtx sync.Mutex
+	translators    = map[*validator.Validate]*ut.UniversalTranslator{}
+)
+
+// Translator 获取指定语言的翻译器，目前支持zh和en，其他语言使用en
+func Translator(v *validator.Validate, locale string) (ut.Translator, error) {
+	if v == nil {
+		v = defaultValidate
+	}
+	translatorsMtx.Lock()
+	defer translatorsMtx.Unlock()
+	uni, ok := translators[v]
+	if !ok {
+		uni = ut.New(en.New(), en.New(), zh.New())
+		enTrans, _ := uni.GetTranslator(LocaleEn)
+		if err := entrans.RegisterDefaultTranslations(v, enTrans); err != nil {
+			return nil, sderr.Wrap(err, "register en translations error")
+		}
+		zhTrans, _ := uni.GetTranslator(LocaleZh)
+		if err := zhtrans.RegisterDefaultTranslations(v, zhTrans); err != nil {
+			return nil, sderr.Wrap(err, "register zh translations error")
+		}
+		translators[v] = uni
+	}
+	trans, _ := uni.FindTranslator(normalizeLocale(locale), LocaleEn)
+	return trans, nil
+}
+
+// Translate 将校验错误翻译为指定语言，err不是校验错误时返回nil，
+// 翻译只对Default()和JSON()产生的错误有效，其他validator的错误使用原始的错误信息
+func Translate(err error, locale string) []FieldErrorInfo {
+	fieldErrs := FieldsErrors(err)
+	if len(fieldErrs) <= 0 {
+		return nil
+	}
+	var transList []ut.Translator
+	for _, v := range []*validator.Validate{jsonValidate, defaultValidate} {
+		if trans, err := Translator(v, locale); err == nil {
+			transList = append(transList, trans)
+		}
+	}
+	infos := make([]FieldErrorInfo, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		info := FieldErrorInfo{
+			Field: fieldPath(fieldErr),
+			Tag:   fieldErr.Tag(),
+			Param: fieldErr.Param(),
+		}
+		info.Message = translateFieldError(fieldErr, transList)
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// translateFieldError 翻译器只对注册它的validator产生的错误有效，否则FieldError.Translate返回Error()
+func translateFieldError(fieldErr validator.FieldError, transList []ut.Translator) string {
+	for _, trans := range transList {
+		if msg := fieldErr.Translate(trans); msg != fieldErr.Error() {
+			return msg
+		}
+	}
+	return fieldErr.Error()
+}
+
+func normalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
+
+// fieldPath 去掉顶层结构体的名称，例如User.profile.name -> profile.name，匿名结构体没有顶层名称
+func fieldPath(fieldErr validator.FieldError) string {
+	ns, structNs := fieldErr.Namespace(), fieldErr.StructNamespace()
+	root, rest, ok := strings.Cut(ns, ".")
+	if !ok {
+		return ns
+	}
+	if structRoot, _, _ := strings.Cut(structNs, "."); structRoot != root {
+		return ns
+	}
+	return rest
+}
@@ -0,0 +1,33 @@
+package sdvalidator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	type profile struct {
+		Age int `json:"age" validate:"gte=18"`
+	}
+	type user struct {
+		Name    string  `json:"name" validate:"required"`
+		Profile profile `json:"profile"`
+	}
+
+	infos := Translate(JSON().Struct(&user{}), "zh-CN")
+	assert.Len(t, infos, 2)
+	assert.Equal(t, FieldErrorInfo{Field: "name", Tag: "required", Message: "name为必填字段"}, infos[0])
+	assert.Equal(t, FieldErrorInfo{Field: "profile.age", Tag: "gte", Param: "18", Message: "age必须大于或等于18"}, infos[1])
+
+	infos = Translate(JSON().Struct(&user{Name: "a"}), "fr")
+	assert.Len(t, infos, 1)
+	assert.Equal(t, "age must be 18 or greater", infos[0].Message)
+
+	// 默认的validator使用字段名
+	infos = Translate(Struct(&user{}), "zh")
+	assert.Len(t, infos, 2)
+	assert.Equal(t, FieldErrorInfo{Field: "Name", Tag: "required", Message: "Name为必填字段"}, infos[0])
+	assert.Equal(t, FieldErrorInfo{Field: "Profile.Age", Tag: "gte", Param: "18", Message: "Age必须大于或等于18"}, infos[1])
+
+	assert.Nil(t, Translate(Struct(&user{Name: "a", Profile: profile{Age: 20}}), "en"))
+}